@@ -0,0 +1,114 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"github.com/gin-gonic/gin"
+)
+
+// Authorizer returns true if a request may receive detailed status (error messages, checks, dependencies, history),
+// otherwise the caller (e.g. the kubelet) receives only the bare status code, see Config.Authorizer
+type Authorizer func(req *http.Request) bool
+
+// BearerTokenAuthorizer authorizes requests with an `Authorization: Bearer <token>` header matching any of tokens
+func BearerTokenAuthorizer(tokens ...string) Authorizer {
+	return func(req *http.Request) bool {
+		header := req.Header.Get("Authorization")
+		if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+			return false
+		}
+		token := []byte(strings.TrimSpace(header[7:]))
+		authorized := false
+		for _, t := range tokens {
+			if t != "" && subtle.ConstantTimeCompare(token, []byte(t)) == 1 {
+				authorized = true
+			}
+		}
+		return authorized
+	}
+}
+
+// ClientCertificateAuthorizer authorizes requests with a verified client certificate (mTLS, see
+// Config.TLSClientCAFile), where the common name or a DNS name matches any of names, or any verified client
+// certificate if names is empty
+func ClientCertificateAuthorizer(names ...string) Authorizer {
+	return func(req *http.Request) bool {
+		if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+			return false
+		}
+		if len(names) == 0 {
+			return true
+		}
+		certificate := req.TLS.VerifiedChains[0][0]
+		for _, name := range names {
+			if name == certificate.Subject.CommonName {
+				return true
+			}
+			for _, dnsName := range certificate.DNSNames {
+				if name == dnsName {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
+
+// AnyAuthorizer authorizes requests authorized by any of authorizers
+func AnyAuthorizer(authorizers ...Authorizer) Authorizer {
+	return func(req *http.Request) bool {
+		for _, authorizer := range authorizers {
+			if authorizer != nil && authorizer(req) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// authorized returns true if the request may receive detailed status
+func (s *Service) authorized(i *gin.Context) bool {
+	return s.config.Authorizer == nil || s.config.Authorizer(i.Request)
+}
+
+// renderBare writes only the outcome of status (and any reason), in the negotiated format, for unauthorized requests
+func (s *Service) renderBare(i *gin.Context, format string, status Status) {
+	setHeaders(i, status)
+	message := "OK"
+	if !status.Success {
+		message = http.StatusText(status.Code)
+	}
+	switch format {
+	case gin.MIMEPlain:
+		i.String(status.Code, "%s\n", strings.ToLower(message))
+	case MIMEHealthJSON:
+		i.Render(status.Code, healthJSON{HealthResponse{Status: healthStatus(status.Success)}})
+	default:
+		i.JSON(status.Code, Status{Code: status.Code, Message: message, Success: status.Success, Reason: status.Reason, RetryAfter: status.RetryAfter})
+	}
+}
+
+// requireAuthorized aborts requests that may not receive detailed status with 401 Unauthorized
+func (s *Service) requireAuthorized(i *gin.Context) {
+	if s.authorized(i) {
+		return
+	}
+	i.AbortWithStatusJSON(http.StatusUnauthorized, Status{Code: http.StatusUnauthorized, Message: http.StatusText(http.StatusUnauthorized)})
+}
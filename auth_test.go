@@ -0,0 +1,225 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBearerTokenAuthorizer(t *testing.T) {
+	authorizer := BearerTokenAuthorizer("one", "", "two")
+	for _, testCase := range []struct {
+		Header     string
+		Authorized bool
+	}{
+		{"", false},
+		{"Bearer", false},
+		{"Bearer ", false},
+		{"Bearer one", true},
+		{"bearer two", true},
+		{"Bearer three", false},
+		{"Basic one", false},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/readiness", nil)
+		if testCase.Header != "" {
+			req.Header.Set("Authorization", testCase.Header)
+		}
+		if authorized := authorizer(req); authorized != testCase.Authorized {
+			t.Error(testCase, authorized)
+		}
+	}
+}
+
+func TestClientCertificateAuthorizer(t *testing.T) {
+	verified := func(commonName string, dnsNames ...string) *tls.ConnectionState {
+		return &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{{
+			Subject:  pkix.Name{CommonName: commonName},
+			DNSNames: dnsNames,
+		}}}}
+	}
+	for _, testCase := range []struct {
+		Authorizer Authorizer
+		TLS        *tls.ConnectionState
+		Authorized bool
+	}{
+		{ClientCertificateAuthorizer(), nil, false},
+		{ClientCertificateAuthorizer(), &tls.ConnectionState{}, false},
+		{ClientCertificateAuthorizer(), verified("any"), true},
+		{ClientCertificateAuthorizer("monitor"), verified("any"), false},
+		{ClientCertificateAuthorizer("monitor"), verified("monitor"), true},
+		{ClientCertificateAuthorizer("monitor.example.com"), verified("any", "monitor.example.com"), true},
+		{AnyAuthorizer(nil, BearerTokenAuthorizer("token"), ClientCertificateAuthorizer()), verified("any"), true},
+		{AnyAuthorizer(), verified("any"), false},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/readiness", nil)
+		req.TLS = testCase.TLS
+		if authorized := testCase.Authorizer(req); authorized != testCase.Authorized {
+			t.Error(testCase.TLS, authorized)
+		}
+	}
+}
+
+func TestService_authorizer(t *testing.T) {
+	config := NewConfig()
+	config.Logger = nil
+	config.HistorySize = DefaultHistorySize
+	config.HistoryPath = DefaultHistoryPath
+	config.VersionPath = DefaultVersionPath
+	config.MetricsPath = "/metrics"
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.ReadinessChecks = []Check{
+		{
+			Name: "database",
+			Handler: func() error {
+				return errors.New("secret.internal:5432 refused")
+			},
+		},
+	}
+	config.Authorizer = BearerTokenAuthorizer("token")
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pretend we started
+	func() {
+		service.mutex.Lock()
+		defer service.mutex.Unlock()
+		service.fatal = FatalError{}
+	}()
+
+	get := func(target string, accept string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		service.engine.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, testCase := range []struct {
+		Target string
+		Accept string
+		Token  string
+		Code   int
+		Body   string
+		Secret bool
+	}{
+		{"/healthz", "", "", 200, `"message":"OK"`, false},
+		{"/readiness", "", "", 503, `"message":"Service Unavailable"`, false},
+		{"/readiness?verbose", "", "", 503, `"message":"Service Unavailable"`, false},
+		{"/readiness?verbose", "text/plain", "", 503, "service unavailable\n", false},
+		{"/readiness", MIMEHealthJSON, "", 503, `{"status":"fail",`, false},
+		{"/readiness/database", "", "", 503, `"message":"Service Unavailable"`, false},
+		{"/readiness/missing", "", "", 503, `"message":"Service Unavailable"`, false},
+		{"/healthz/missing", "", "", 200, `"message":"OK"`, false},
+		{"/readiness?exclude=database", "", "", 503, `"message":"Service Unavailable"`, false},
+		{"/readiness", "", "wrong", 503, `"message":"Service Unavailable"`, false},
+		{"/readiness", "", "token", 503, "", true},
+		{"/readiness?verbose", "text/plain", "token", 503, "[-]database failed", true},
+		{"/readiness/database", "", "token", 503, "", true},
+		{"/readiness/missing", "", "token", 404, "unknown check: missing", false},
+		{"/readiness?exclude=database", "", "token", 200, `"message":"OK"`, false},
+		{"/history", "", "", 401, "Unauthorized", false},
+		{"/history", "", "token", 200, "", true},
+		{"/metrics", "", "", 401, "Unauthorized", false},
+		{"/metrics", "", "token", 200, "kubestatus_", false},
+		{"/version", "", "", 401, "Unauthorized", false},
+		{"/version", "", "token", 200, "goVersion", false},
+	} {
+		w := get(testCase.Target, testCase.Accept, testCase.Token)
+		body := w.Body.String()
+		if w.Code != testCase.Code || !strings.Contains(body, testCase.Body) || strings.Contains(body, "secret.internal") != testCase.Secret {
+			t.Error(testCase.Target, testCase.Token, w.Code, body)
+		}
+	}
+}
+
+func TestService_authorizerClientCertificate(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kubestatus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cert, key := generateCertificate(t, "monitor", time.Now().Add(time.Hour))
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	for file, b := range map[string][]byte{certFile: cert, keyFile: key} {
+		if err := os.WriteFile(file, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := NewConfig()
+	config.Port = 9070
+	config.Logger = nil
+	config.HealthHandler = func() error {
+		return errors.New("detailed failure")
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.TLSCertFile = certFile
+	config.TLSKeyFile = keyFile
+	config.TLSClientCAFile = certFile
+	config.Authorizer = ClientCertificateAuthorizer("monitor")
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	withoutCert, err := LoadClientTLSConfig(certFile, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = (Client{Addresses: []string{config.URL()}, TLSConfig: withoutCert}).Health()
+	if err == nil || strings.Contains(err.Error(), "detailed failure") {
+		t.Error(err)
+	}
+
+	withCert, err := LoadClientTLSConfig(certFile, certFile, keyFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = (Client{Addresses: []string{config.URL()}, TLSConfig: withCert}).Health()
+	if err == nil || !strings.Contains(err.Error(), "detailed failure") {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,189 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultCircuitFailureThreshold = 5
+	DefaultCircuitOpenTimeout      = time.Second * 30
+)
+
+const (
+	// CircuitClosed allows all requests, the initial state
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails all requests fast, until the open timeout has elapsed
+	CircuitOpen
+	// CircuitHalfOpen allows a single probe request, which will close the circuit if it succeeds, or open it again
+	// if it fails
+	CircuitHalfOpen
+)
+
+type (
+	// CircuitState is the state of the circuit for a single address
+	CircuitState int
+
+	// CircuitBreaker tracks a circuit per address, failing fast after consecutive failures, and periodically allowing
+	// a single probe, it must not be copied after first use, see Client.Breaker and Config.DependencyBreaker
+	CircuitBreaker struct {
+		// FailureThreshold is the number of consecutive failures that will open the circuit, defaults to
+		// DefaultCircuitFailureThreshold
+		FailureThreshold int
+
+		// OpenTimeout is how long the circuit stays open before a probe is allowed, defaults to
+		// DefaultCircuitOpenTimeout
+		OpenTimeout time.Duration
+
+		mutex    sync.Mutex
+		circuits map[string]*circuit
+	}
+
+	// CircuitOpenError is returned for requests to an address while it's circuit is open
+	CircuitOpenError struct {
+		// Address is the address that failed fast
+		Address string
+
+		// Until is when a probe request will next be allowed
+		Until time.Time
+	}
+
+	circuit struct {
+		state    CircuitState
+		failures int
+		opened   time.Time
+		probing  bool
+	}
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("kubestatus.CircuitBreaker circuit open for %q, next probe after %s", e.Address, e.Until.Format(time.RFC3339))
+}
+
+func (b *CircuitBreaker) failureThreshold() int {
+	if b.FailureThreshold <= 0 {
+		return DefaultCircuitFailureThreshold
+	}
+	return b.FailureThreshold
+}
+
+func (b *CircuitBreaker) openTimeout() time.Duration {
+	if b.OpenTimeout <= 0 {
+		return DefaultCircuitOpenTimeout
+	}
+	return b.OpenTimeout
+}
+
+// circuit returns the circuit for an address, the mutex must be held
+func (b *CircuitBreaker) circuit(address string) *circuit {
+	if b.circuits == nil {
+		b.circuits = make(map[string]*circuit)
+	}
+	c, ok := b.circuits[address]
+	if !ok {
+		c = new(circuit)
+		b.circuits[address] = c
+	}
+	return c
+}
+
+// State returns the current state of the circuit for an address
+func (b *CircuitBreaker) State(address string) CircuitState {
+	if b == nil {
+		return CircuitClosed
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	c := b.circuit(address)
+	if c.state == CircuitOpen && !time.Now().Before(c.opened.Add(b.openTimeout())) {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// allow returns a *CircuitOpenError if a request to the address should fail fast, otherwise the request must be
+// followed by a call to either record or abort
+func (b *CircuitBreaker) allow(address string) error {
+	if b == nil {
+		return nil
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	c := b.circuit(address)
+	until := c.opened.Add(b.openTimeout())
+	switch c.state {
+	case CircuitOpen:
+		if time.Now().Before(until) {
+			return &CircuitOpenError{Address: address, Until: until}
+		}
+		c.state = CircuitHalfOpen
+		c.probing = true
+	case CircuitHalfOpen:
+		if c.probing {
+			return &CircuitOpenError{Address: address, Until: until}
+		}
+		c.probing = true
+	}
+	return nil
+}
+
+// record updates the circuit for an address with the outcome of an allowed request
+func (b *CircuitBreaker) record(address string, err error) {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	c := b.circuit(address)
+	c.probing = false
+	if err == nil {
+		c.state = CircuitClosed
+		c.failures = 0
+		return
+	}
+	c.failures++
+	if c.state == CircuitHalfOpen || c.failures >= b.failureThreshold() {
+		c.state = CircuitOpen
+		c.opened = time.Now()
+	}
+}
+
+// abort releases an allowed request without recording an outcome, e.g. if the caller's context was cancelled
+func (b *CircuitBreaker) abort(address string) {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.circuit(address).probing = false
+}
@@ -0,0 +1,139 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var b *CircuitBreaker
+	if err := b.allow("a"); err != nil || b.State("a") != CircuitClosed {
+		t.Fatal(err)
+	}
+
+	b = &CircuitBreaker{FailureThreshold: 2, OpenTimeout: time.Millisecond * 50}
+	failure := errors.New("failure")
+
+	for i := 0; i < 2; i++ {
+		if err := b.allow("a"); err != nil {
+			t.Fatal(i, err)
+		}
+		b.record("a", failure)
+	}
+	if state := b.State("a"); state != CircuitOpen {
+		t.Fatal(state)
+	}
+	if state := b.State("b"); state != CircuitClosed {
+		t.Fatal(state)
+	}
+	if err, ok := b.allow("a").(*CircuitOpenError); !ok || err.Address != "a" || !strings.Contains(err.Error(), "circuit open") {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Millisecond * 60)
+
+	if state := b.State("a"); state != CircuitHalfOpen {
+		t.Fatal(state)
+	}
+	// only a single probe is allowed
+	if err := b.allow("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.allow("a"); err == nil {
+		t.Fatal("expected error")
+	}
+	// a failed probe opens the circuit again
+	b.record("a", failure)
+	if err := b.allow("a"); err == nil {
+		t.Fatal("expected error")
+	}
+
+	time.Sleep(time.Millisecond * 60)
+
+	// an aborted probe allows another probe
+	if err := b.allow("a"); err != nil {
+		t.Fatal(err)
+	}
+	b.abort("a")
+	if err := b.allow("a"); err != nil {
+		t.Fatal(err)
+	}
+	b.record("a", nil)
+	if state := b.State("a"); state != CircuitClosed {
+		t.Fatal(state)
+	}
+
+	// failures must be consecutive
+	b.record("a", failure)
+	b.record("a", nil)
+	b.record("a", failure)
+	if state := b.State("a"); state != CircuitClosed {
+		t.Fatal(state)
+	}
+}
+
+func TestClient_Get_breaker(t *testing.T) {
+	var (
+		requests int32
+		ready    int32
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&ready) == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(Status{Code: http.StatusServiceUnavailable, Message: "down"})
+			return
+		}
+		json.NewEncoder(w).Encode(Status{Success: true, Code: http.StatusOK})
+	}))
+	defer server.Close()
+
+	client := Client{
+		Addresses: []string{server.URL},
+		Breaker:   &CircuitBreaker{FailureThreshold: 3, OpenTimeout: time.Millisecond * 50},
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err := client.Readiness(); err == nil {
+			t.Fatal(i)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatal(n)
+	}
+	if _, err := client.Readiness(); err == nil || !strings.Contains(err.Error(), "circuit open") {
+		t.Fatal(err)
+	}
+
+	atomic.StoreInt32(&ready, 1)
+	time.Sleep(time.Millisecond * 60)
+
+	if _, err := client.Readiness(); err != nil {
+		t.Fatal(err)
+	}
+	if state := client.Breaker.State(server.URL); state != CircuitClosed {
+		t.Fatal(state)
+	}
+}
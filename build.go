@@ -0,0 +1,109 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"net/http"
+	"runtime/debug"
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultVersionPath is a suggested value for Config.VersionPath, which is not served by default
+const DefaultVersionPath = "/version"
+
+// BuildInfo identifies the build of the running service
+type BuildInfo struct {
+	// Version is the version of the service, which defaults to the main module's version
+	Version string `json:"version,omitempty"`
+
+	// Module is the path of the main module
+	Module string `json:"module,omitempty"`
+
+	// ModuleVersion is the version of the main module, e.g. `(devel)` or `v1.2.3`
+	ModuleVersion string `json:"moduleVersion,omitempty"`
+
+	// Revision is the VCS revision of the build
+	Revision string `json:"revision,omitempty"`
+
+	// Time is the VCS commit time of the build, in RFC3339 format
+	Time string `json:"time,omitempty"`
+
+	// Modified will be true if the VCS working tree had local modifications
+	Modified bool `json:"modified,omitempty"`
+
+	// GoVersion is the version of Go that built the binary
+	GoVersion string `json:"goVersion,omitempty"`
+}
+
+// readBuildInfo reads the build info from the binary, with any non-empty fields from override taking precedence
+func readBuildInfo(override BuildInfo) BuildInfo {
+	var build BuildInfo
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		build.Module = info.Main.Path
+		build.ModuleVersion = info.Main.Version
+		build.GoVersion = info.GoVersion
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				build.Revision = setting.Value
+			case "vcs.time":
+				build.Time = setting.Value
+			case "vcs.modified":
+				build.Modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if override.Version != "" {
+		build.Version = override.Version
+	}
+	if override.Module != "" {
+		build.Module = override.Module
+	}
+	if override.ModuleVersion != "" {
+		build.ModuleVersion = override.ModuleVersion
+	}
+	if override.Revision != "" {
+		build.Revision = override.Revision
+	}
+	if override.Time != "" {
+		build.Time = override.Time
+	}
+	if override.Modified {
+		build.Modified = true
+	}
+	if override.GoVersion != "" {
+		build.GoVersion = override.GoVersion
+	}
+
+	if build.Version == "" && build.ModuleVersion != "(devel)" {
+		build.Version = build.ModuleVersion
+	}
+
+	return build
+}
+
+// Build returns the build info for this service
+func (s *Service) Build() BuildInfo {
+	s.ensure()
+	return s.build
+}
+
+func (s *Service) versionHandler(i *gin.Context) {
+	i.JSON(http.StatusOK, s.build)
+}
@@ -0,0 +1,80 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestService_Build(t *testing.T) {
+	config := NewConfig()
+	config.Logger = nil
+	config.StatusBuild = true
+	config.VersionPath = DefaultVersionPath
+	config.Build = BuildInfo{Version: "1.2.3", Revision: "abc123"}
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	build := service.Build()
+	if build.Version != "1.2.3" || build.Revision != "abc123" || build.GoVersion != runtime.Version() {
+		t.Error(build)
+	}
+
+	if status := service.Health(); status.Build != build {
+		t.Error(status)
+	}
+
+	w := httptest.NewRecorder()
+	service.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/version", nil))
+	var actual BuildInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &actual); err != nil || w.Code != 200 || actual != build {
+		t.Error(w.Code, w.Body.String(), err)
+	}
+
+	// disabled by default
+	config.StatusBuild = false
+	service, err = NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status := service.Health(); status.Build != (BuildInfo{}) {
+		t.Error(status)
+	}
+	b, err := json.Marshal(service.Health())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(b, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["build"]; ok {
+		t.Error(string(b))
+	}
+}
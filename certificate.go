@@ -0,0 +1,118 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// CertificateChecker checks for expired or soon to expire certificates, it's Check method may be used as a
+// HealthHandler or ReadinessHandler, and will fail for the first certificate that is within Window of expiry
+type CertificateChecker struct {
+	// Files are paths to PEM encoded certificates, which are read on every check, all certificates in each file
+	// (the chain) will be checked
+	Files []string
+
+	// TLSConfig may be set to check all certificates (and chains) configured in TLSConfig.Certificates
+	TLSConfig *tls.Config
+
+	// Window is how long before a certificate's NotAfter that it will be considered failing, a zero value will only
+	// fail expired certificates
+	Window time.Duration
+}
+
+// Check returns an error if any certificate could not be loaded, or is within the configured window of expiry, the
+// error message will include the subject and NotAfter of the certificate
+func (c CertificateChecker) Check() error {
+	deadline := time.Now().Add(c.Window)
+
+	check := func(source string, certificates []*x509.Certificate) error {
+		for _, certificate := range certificates {
+			if !certificate.NotAfter.After(deadline) {
+				return fmt.Errorf(
+					"kubestatus.CertificateChecker certificate from %s expires within %s: subject=%q not_after=%s",
+					source,
+					c.Window,
+					certificate.Subject.String(),
+					certificate.NotAfter.UTC().Format(time.RFC3339),
+				)
+			}
+		}
+		return nil
+	}
+
+	for _, file := range c.Files {
+		certificates, err := loadCertificates(file)
+		if err != nil {
+			return fmt.Errorf("kubestatus.CertificateChecker failed to load %q: %s", file, err.Error())
+		}
+		if err := check(fmt.Sprintf("%q", file), certificates); err != nil {
+			return err
+		}
+	}
+
+	if c.TLSConfig != nil {
+		for i, chain := range c.TLSConfig.Certificates {
+			certificates := make([]*x509.Certificate, 0, len(chain.Certificate))
+			for _, der := range chain.Certificate {
+				certificate, err := x509.ParseCertificate(der)
+				if err != nil {
+					return fmt.Errorf("kubestatus.CertificateChecker failed to parse tls config certificate %d: %s", i, err.Error())
+				}
+				certificates = append(certificates, certificate)
+			}
+			if err := check(fmt.Sprintf("tls config certificate %d", i), certificates); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func loadCertificates(file string) ([]*x509.Certificate, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var certificates []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, certificate)
+	}
+	if len(certificates) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+	return certificates, nil
+}
@@ -0,0 +1,118 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// generateCertificate creates a self signed certificate valid for localhost, returning the PEM encoded certificate
+// and key
+func generateCertificate(t *testing.T, commonName string, notAfter time.Time) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestCertificateChecker_Check(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kubestatus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	validCert, validKey := generateCertificate(t, "valid", time.Now().Add(time.Hour*24*30))
+	expiringCert, _ := generateCertificate(t, "expiring", time.Now().Add(time.Hour))
+
+	validFile := filepath.Join(dir, "valid.pem")
+	if err := os.WriteFile(validFile, validCert, 0600); err != nil {
+		t.Fatal(err)
+	}
+	chainFile := filepath.Join(dir, "chain.pem")
+	if err := os.WriteFile(chainFile, append(append([]byte(nil), validCert...), expiringCert...), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(keyFile, validKey, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	validPair, err := tls.X509KeyPair(validCert, validKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, testCase := range []struct {
+		Checker CertificateChecker
+		Error   string
+	}{
+		{CertificateChecker{}, ""},
+		{CertificateChecker{Files: []string{validFile}, Window: time.Hour * 24}, ""},
+		{CertificateChecker{Files: []string{validFile}, Window: time.Hour * 24 * 60}, `subject="CN=valid"`},
+		{CertificateChecker{Files: []string{chainFile}}, ""},
+		{CertificateChecker{Files: []string{chainFile}, Window: time.Hour * 24}, `subject="CN=expiring"`},
+		{CertificateChecker{Files: []string{keyFile}}, "no certificates found"},
+		{CertificateChecker{Files: []string{filepath.Join(dir, "missing.pem")}}, "failed to load"},
+		{CertificateChecker{TLSConfig: &tls.Config{Certificates: []tls.Certificate{validPair}}, Window: time.Hour}, ""},
+		{CertificateChecker{TLSConfig: &tls.Config{Certificates: []tls.Certificate{validPair}}, Window: time.Hour * 24 * 60}, "tls config certificate 0"},
+	} {
+		err := testCase.Checker.Check()
+		if testCase.Error == "" {
+			if err != nil {
+				t.Error(testCase, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), testCase.Error) {
+			t.Error(testCase, err)
+		}
+	}
+}
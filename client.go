@@ -17,12 +17,18 @@
 package kubestatus
 
 import (
-	"errors"
+	"context"
+	"crypto/tls"
 	"net/http"
 	"fmt"
 	"encoding/json"
 	"net/url"
 	"strings"
+	"time"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Client provides an interface to the server, for nested readiness checks, for example, providing short-circuiting
@@ -36,6 +42,64 @@ type Client struct {
 
 	// UUIDs will be passed in via the query parameter
 	UUIDs []string
+
+	// TracerProvider is used to create a span per address, defaults to the global provider
+	TracerProvider trace.TracerProvider
+
+	// Propagator is used to propagate the trace context to each address, defaults to W3C trace context
+	Propagator propagation.TextMapPropagator
+
+	// Retry configures retrying each address, the zero value makes a single attempt
+	Retry RetryPolicy
+
+	// Breaker may be set to fail fast (with a *CircuitOpenError) for addresses that are consistently failing, it
+	// should be shared between clients, as it tracks the state of each address
+	Breaker *CircuitBreaker
+
+	// TLSConfig may be set to configure TLS for `https` addresses, e.g. a private CA, a client certificate (mTLS),
+	// or the server name, see kubestatus.LoadClientTLSConfig
+	TLSConfig *tls.Config
+
+	// Header may be set to add static headers to every request
+	Header http.Header
+
+	// Credentials may be set to add (possibly rotating) headers to every request, e.g. kubestatus.BearerTokenFile,
+	// which take precedence over Header, they are only sent to `https` addresses, unless AllowInsecureCredentials
+	Credentials CredentialsProvider
+
+	// AllowInsecureCredentials, if true, will send Credentials to `http` addresses, i.e. in plaintext
+	AllowInsecureCredentials bool
+
+	// HTTPClient may be set to override the http client used for all requests, in which case TLSConfig is ignored
+	HTTPClient *http.Client
+
+	// HealthPath is the path used by Health, defaults to DefaultHealthPath
+	HealthPath string
+
+	// ReadinessPath is the path used by Readiness, defaults to DefaultReadinessPath
+	ReadinessPath string
+}
+
+// ClientError is returned by Client for any response with a status code not in the 200 range
+type ClientError struct {
+	// Address is the address that responded
+	Address string
+
+	// Code is the HTTP status code of the response
+	Code int
+
+	// Reason is the machine-readable reason from the response, if any, see Status.Reason
+	Reason Reason
+
+	// Status is the decoded response, if any
+	Status *Status
+
+	message string
+}
+
+// Error implements error
+func (e *ClientError) Error() string {
+	return e.message
 }
 
 func statusOK(status int) bool {
@@ -51,73 +115,193 @@ func statusOK(status int) bool {
 // Get hits the endpoint on all clients, and returns any statuses (if valid json responses are returned and can be
 // deserialized), a non-nil error will be returned if any clients return a status not in the 200 range.
 func (c Client) Get(endpoint string) ([]*Status, error) {
+	return c.GetContext(context.Background(), endpoint)
+}
+
+// GetContext is Get, with a context, which is used for the requests, and as the parent of any spans
+func (c Client) GetContext(ctx context.Context, endpoint string) ([]*Status, error) {
 	var (
 		statuses = make([]*Status, len(c.Addresses))
 		err      error
 	)
 
-	for i, address := range c.Addresses {
-		var (
-			httpResp *http.Response
-			httpErr  error
-			URL      *url.URL
-		)
+	// connections are reused for the duration of the call, use HTTPClient to reuse them across calls
+	if c.HTTPClient == nil && c.TLSConfig != nil {
+		c.HTTPClient = newTLSClient(c.TLSConfig)
+		defer c.HTTPClient.CloseIdleConnections()
+	}
 
-		URL, httpErr = url.Parse(address)
+	for i, address := range c.Addresses {
+		var httpErr error
 
-		if httpErr == nil {
-			URL.Path += endpoint
+		statuses[i], httpErr = c.get(ctx, address, endpoint)
 
-			if len(c.UUIDs) != 0 {
-				query := URL.Query()
-				query.Set("uuids", strings.Join(c.UUIDs, ","))
-				URL.RawQuery = query.Encode()
+		if httpErr != nil {
+			if err == nil {
+				err = httpErr
 			}
 
-			httpResp, httpErr = http.Get(URL.String())
+			if !c.All {
+				break
+			}
 		}
+	}
 
-		if httpErr == nil {
-			if !statusOK(httpResp.StatusCode) {
-				httpErr = errors.New(httpResp.Status)
-			}
+	return statuses, err
+}
+
+// get hits the endpoint on a single address, retrying as per c.Retry
+func (c Client) get(ctx context.Context, address string, endpoint string) (*Status, error) {
+	ctx, span := tracer(c.TracerProvider).Start(
+		ctx,
+		"kubestatus.Client.Get",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("kubestatus.address", RedactURLUserinfo(address)),
+			attribute.String("kubestatus.endpoint", endpoint),
+		),
+	)
+	defer span.End()
 
-			status := new(Status)
-			decoder := json.NewDecoder(httpResp.Body)
+	if err := c.Breaker.allow(address); err != nil {
+		span.SetStatus(codes.Error, RedactURLUserinfo(err.Error()))
+		return nil, err
+	}
 
-			if err := decoder.Decode(status); err == nil {
-				statuses[i] = status
+	var (
+		status    *Status
+		err       error
+		retryable bool
+		attempt   int
+	)
 
-				if httpErr != nil && status.Message != "" {
-					httpErr = fmt.Errorf("%s: %s", httpErr.Error(), status.Message)
-				}
-			}
+	for attempt = 1; ; attempt++ {
+		status, retryable, err = c.attempt(ctx, address, endpoint)
 
-			httpResp.Body.Close()
+		if err == nil || !retryable || attempt >= c.Retry.MaxAttempts || ctx.Err() != nil {
+			break
 		}
 
-		if httpErr != nil {
-			if err == nil {
-				err = httpErr
-			}
+		timer := time.NewTimer(c.Retry.backoff(attempt))
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		timer.Stop()
 
-			if !c.All {
-				break
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if ctx.Err() != nil {
+		// the caller gave up, which says nothing about the address
+		c.Breaker.abort(address)
+	} else {
+		c.Breaker.record(address, err)
+	}
+
+	span.SetAttributes(attribute.Int("kubestatus.attempts", attempt))
+	if status != nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", status.Code))
+	}
+	if err != nil {
+		span.SetStatus(codes.Error, RedactURLUserinfo(err.Error()))
+	}
+
+	return status, err
+}
+
+// attempt makes a single request, returning any decoded status, and if the error (if any) may be retried
+func (c Client) attempt(ctx context.Context, address string, endpoint string) (*Status, bool, error) {
+	URL, err := url.Parse(address)
+	if err != nil {
+		return nil, false, err
+	}
+
+	URL.Path += endpoint
+
+	if len(c.UUIDs) != 0 {
+		query := URL.Query()
+		query.Set("uuids", strings.Join(c.UUIDs, ","))
+		URL.RawQuery = query.Encode()
+	}
+
+	if c.Retry.AttemptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Retry.AttemptTimeout)
+		defer cancel()
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, URL.String(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+
+	header, err := c.header(ctx, address, URL.Scheme == "https")
+	if err != nil {
+		return nil, false, err
+	}
+	for key, values := range header {
+		httpReq.Header[key] = values
+	}
+
+	propagator(c.Propagator).Inject(ctx, propagation.HeaderCarrier(httpReq.Header))
+
+	httpResp, err := c.httpClient().Do(httpReq)
+	if err != nil {
+		// connection errors (including attempt timeouts) may be retried
+		return nil, true, err
+	}
+	defer httpResp.Body.Close()
+
+	var result *Status
+	status := new(Status)
+	decoder := json.NewDecoder(httpResp.Body)
+
+	if err := decoder.Decode(status); err == nil {
+		result = status
+	}
+
+	if statusOK(httpResp.StatusCode) {
+		return result, false, nil
+	}
+
+	httpErr := &ClientError{
+		Address: address,
+		Code:    httpResp.StatusCode,
+		Status:  result,
+		message: httpResp.Status,
+	}
+
+	if result != nil {
+		httpErr.Reason = result.Reason
+		if result.Message != "" {
+			if result.Pod.Name != "" {
+				httpErr.message = fmt.Sprintf("%s: pod %s: %s", httpResp.Status, result.Pod.String(), result.Message)
+			} else {
+				httpErr.message = fmt.Sprintf("%s: %s", httpResp.Status, result.Message)
 			}
 		}
 	}
 
-	return statuses, err
+	return result, c.Retry.retryable(httpResp.StatusCode), httpErr
 }
 
-// Health hits `/healthz` returns a status slice of equal length to the addresses, with returned statuses for each
-// (or nil), and the first error encountered (if any)
+// Health hits `/healthz` (or HealthPath) returns a status slice of equal length to the addresses, with returned
+// statuses for each (or nil), and the first error encountered (if any)
 func (c Client) Health() ([]*Status, error) {
-	return c.Get("/healthz")
+	if c.HealthPath != "" {
+		return c.Get(c.HealthPath)
+	}
+	return c.Get(DefaultHealthPath)
 }
 
-// Readiness hits `/readiness` returns a status slice of equal length to the addresses, with returned statuses for each
-// (or nil), and the first error encountered (if any)
+// Readiness hits `/readiness` (or ReadinessPath) returns a status slice of equal length to the addresses, with
+// returned statuses for each (or nil), and the first error encountered (if any)
 func (c Client) Readiness() ([]*Status, error) {
-	return c.Get("/readiness")
+	if c.ReadinessPath != "" {
+		return c.Get(c.ReadinessPath)
+	}
+	return c.Get(DefaultReadinessPath)
 }
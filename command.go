@@ -0,0 +1,138 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	DefaultCommandTimeout   = time.Second * 10
+	DefaultCommandMaxOutput = 512
+)
+
+// CommandChecker runs a command (e.g. a legacy health script), it's Check method may be used as a HealthHandler or
+// ReadinessHandler, and will fail for any exit code other than 0
+type CommandChecker struct {
+	// Path is the name or path of the command to run
+	Path string
+
+	// Args are the arguments passed to the command
+	Args []string
+
+	// Env are additional environment variables in the form key=value, appended to the current process environment
+	Env []string
+
+	// Dir is the working directory of the command, which defaults to the current directory
+	Dir string
+
+	// Timeout is how long the command may run before it is killed, defaults to DefaultCommandTimeout
+	Timeout time.Duration
+
+	// MaxOutput is the maximum number of bytes of (combined) stdout and stderr to include in the error, defaults to
+	// DefaultCommandMaxOutput
+	MaxOutput int
+}
+
+// Check runs the command, returning an error if it could not be run, timed out, or exited non-zero, which will
+// include the (truncated) output of the command
+func (c CommandChecker) Check() error {
+	if c.Path == "" {
+		return errors.New("kubestatus.CommandChecker empty path")
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCommandTimeout
+	}
+
+	maxOutput := c.MaxOutput
+	if maxOutput <= 0 {
+		maxOutput = DefaultCommandMaxOutput
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// capture a little more than maxOutput, so it can be truncated on a rune boundary
+	output := &limitedBuffer{limit: maxOutput + utf8.UTFMax}
+	cmd := exec.CommandContext(ctx, c.Path, c.Args...)
+	cmd.Dir = c.Dir
+	cmd.Stdout = output
+	cmd.Stderr = output
+	// don't wait on any orphaned children holding the output open, after the timeout
+	cmd.WaitDelay = time.Millisecond * 100
+	if len(c.Env) != 0 {
+		cmd.Env = append(os.Environ(), c.Env...)
+	}
+
+	err := cmd.Run()
+	if err == nil {
+		return nil
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		err = timeoutError{fmt.Errorf("timed out after %s", timeout)}
+	}
+
+	var suffix string
+	if out := strings.TrimSpace(output.buffer.String()); out != "" {
+		if len(out) > maxOutput || output.truncated {
+			out = truncate(out, maxOutput) + "..."
+		}
+		suffix = ": " + out
+	}
+
+	return fmt.Errorf("kubestatus.CommandChecker %q failed (%w)%s", c.Path, err, suffix)
+}
+
+// limitedBuffer captures up to limit bytes, discarding (but accepting) the rest
+type limitedBuffer struct {
+	limit     int
+	buffer    bytes.Buffer
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buffer.Len(); remaining < len(p) {
+		b.truncated = true
+		if remaining > 0 {
+			b.buffer.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.buffer.Write(p)
+}
+
+// truncate returns s cut to at most n bytes, on a rune boundary
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
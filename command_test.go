@@ -0,0 +1,109 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCommandChecker_Check(t *testing.T) {
+	for _, testCase := range []struct {
+		Checker CommandChecker
+		Error   string
+	}{
+		{CommandChecker{}, "kubestatus.CommandChecker empty path"},
+		{CommandChecker{Path: "sh", Args: []string{"-c", "echo ok"}}, ""},
+		{
+			CommandChecker{Path: "sh", Args: []string{"-c", "echo some_error >&2; exit 3"}},
+			`kubestatus.CommandChecker "sh" failed (exit status 3): some_error`,
+		},
+		{
+			CommandChecker{Path: "sh", Args: []string{"-c", `test "$SOME_VAR" = some_value`}, Env: []string{"SOME_VAR=some_value"}},
+			"",
+		},
+		{
+			CommandChecker{Path: "sh", Args: []string{"-c", "echo 0123456789; exit 1"}, MaxOutput: 4},
+			`kubestatus.CommandChecker "sh" failed (exit status 1): 0123...`,
+		},
+		{
+			CommandChecker{Path: "sh", Args: []string{"-c", "printf 'ab\\303\\251cd'; exit 1"}, MaxOutput: 3},
+			`kubestatus.CommandChecker "sh" failed (exit status 1): ab...`,
+		},
+		{
+			CommandChecker{Path: "sh", Args: []string{"-c", "yes noisy | head -c 10000000; exit 1"}, MaxOutput: 5},
+			`kubestatus.CommandChecker "sh" failed (exit status 1): noisy...`,
+		},
+		{
+			CommandChecker{Path: "sh", Args: []string{"-c", "sleep 5"}, Timeout: time.Millisecond * 50},
+			`kubestatus.CommandChecker "sh" failed (timed out after 50ms)`,
+		},
+	} {
+		err := testCase.Checker.Check()
+		if testCase.Error == "" {
+			if err != nil {
+				t.Error(testCase, err)
+			}
+		} else if err == nil || err.Error() != testCase.Error {
+			t.Error(testCase, err)
+		}
+	}
+}
+
+func TestCommandChecker_Check_timeout(t *testing.T) {
+	err := CommandChecker{Path: "sh", Args: []string{"-c", "sleep 5"}, Timeout: time.Millisecond * 50}.Check()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+	var statusErr *StatusError
+	if !errors.As(withReason(err, ReasonCheckFailed), &statusErr) || statusErr.Reason != ReasonTimeout {
+		t.Error(statusErr)
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	b := &limitedBuffer{limit: 5}
+	for _, p := range []string{"abc", "def", "ghi"} {
+		if n, err := b.Write([]byte(p)); n != len(p) || err != nil {
+			t.Fatal(n, err)
+		}
+	}
+	if b.buffer.String() != "abcde" || !b.truncated {
+		t.Error(b.buffer.String(), b.truncated)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	for _, testCase := range []struct {
+		Input  string
+		N      int
+		Output string
+	}{
+		{"abc", 5, "abc"},
+		{"abc", 3, "abc"},
+		{"abcd", 3, "abc"},
+		{"ab\u00e9cd", 3, "ab"},
+		{"ab\u00e9cd", 4, "ab\u00e9"},
+		{"\u4e16\u754c", 2, ""},
+	} {
+		if output := truncate(testCase.Input, testCase.N); output != testCase.Output {
+			t.Errorf("%q %d: %q", testCase.Input, testCase.N, output)
+		}
+	}
+}
@@ -18,16 +18,34 @@
 package kubestatus
 
 import (
+	"crypto/tls"
 	"fmt"
+	"log/slog"
 	"time"
 	"errors"
 	"net/url"
+	"strings"
+	"net/http"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const (
 	DefaultPort      = 8080
 	DefaultStartWait = time.Millisecond * 100
+
+	DefaultHealthPath    = "/healthz"
+	DefaultReadinessPath = "/readiness"
+
+	// EndpointHealth identifies the `/healthz` endpoint
+	EndpointHealth Endpoint = "health"
+
+	// EndpointReadiness identifies the `/readiness` endpoint
+	EndpointReadiness Endpoint = "readiness"
+
+	// HandlerCheckName is the reserved check name used to report the HealthHandler and ReadinessHandler
+	HandlerCheckName = "handler"
 )
 
 type (
@@ -37,6 +55,18 @@ type (
 	// ReadinessHandler should return an error if the service is not ready
 	ReadinessHandler func() error
 
+	// Endpoint identifies either the health or readiness of the service
+	Endpoint string
+
+	// Check is a named check, that contributes to either the health or readiness of the service
+	Check struct {
+		// Name identifies the check, and must be unique per endpoint
+		Name string
+
+		// Handler should return an error if the check is failing
+		Handler func() error
+	}
+
 	// Config provides configuration of the status http server.
 	Config struct {
 		// Port is the tcp port to serve the http server
@@ -45,6 +75,12 @@ type (
 		// Hostname is the hostname fragment for the http server, which defaults to an empty string (all)
 		Hostname string
 
+		// Listeners may be set to run multiple servers, e.g. to serve `/healthz` on a loopback or admin port, and
+		// `/readiness` on the pod IP, or on a unix domain socket, replacing the default listener (on Hostname and
+		// Port), where each listener's failure is tracked independently (see kubestatus.Service.ListenerFatal), and
+		// only fails the endpoints it serves, the service's FatalError is set once all listeners have stopped
+		Listeners []Listener
+
 		// StartWait is how long the kubestatus.Service.Start operation will block after starting the server
 		StartWait time.Duration
 
@@ -54,16 +90,143 @@ type (
 		// ReadinessHandler should return an error if the service is not ready
 		ReadinessHandler ReadinessHandler
 
+		// HealthChecks are named checks that must all pass, after the HealthHandler, for the service to be healthy
+		HealthChecks []Check
+
+		// ReadinessChecks are named checks that must all pass, after the ReadinessHandler, for the service to be ready
+		ReadinessChecks []Check
+
+		// HealthPath is the route of the health endpoint, defaults to DefaultHealthPath
+		HealthPath string
+
+		// HealthAliases are additional routes for the health endpoint, e.g. `/livez`
+		HealthAliases []string
+
+		// ReadinessPath is the route of the readiness endpoint, defaults to DefaultReadinessPath
+		ReadinessPath string
+
+		// ReadinessAliases are additional routes for the readiness endpoint, e.g. `/readyz`, note that routes may not
+		// be nested under the routes of either endpoint, as they serve single checks, e.g. `/healthz/{check}`
+		ReadinessAliases []string
+
+		// PathPrefix may be set to prefix all routes, e.g. `/status`, including the version, history and metrics
+		PathPrefix string
+
 		// GinHandlers defines middleware to use
 		GinHandlers []gin.HandlerFunc
 
+		// Logger, if set, is used to log state transitions of endpoints and checks, fatal errors, failed workers,
+		// and any unsuccessful requests
+		Logger *slog.Logger
+
+		// LogSuccess, if true, will also log successful requests, which are excluded by default, as they are mostly
+		// probes
+		LogSuccess bool
+
 		// Dependencies should be an array of addresses (including scheme) that are the root part of `/readiness`
 		// endpoints, note that the `uuids` query parameter will be set, appending configured service's UUID on the
-		// end of any existing `uuids` passed in with the original `/readiness` GET
+		// end of any existing `uuids` passed in with the original `/readiness` GET, addresses with the `grpc` scheme
+		// will instead be checked using grpc.health.v1.Health/Check, in the form `grpc://host:port/service?timeout=1s`,
+		// where the (optional) path is the gRPC service name, and the (optional) timeout defaults to
+		// DefaultGRPCDependencyTimeout
 		Dependencies []string
 
+		// DependencyRetry configures retrying `/readiness` requests to (http) dependencies
+		DependencyRetry RetryPolicy
+
+		// DependencyPath is the path appended to each (http) dependency address, defaults to DefaultReadinessPath,
+		// note that a path prefix may be included in the address itself
+		DependencyPath string
+
+		// DependencyBreaker may be set to fail fast for dependencies that are consistently failing, probing them
+		// only periodically, see CircuitBreaker
+		DependencyBreaker *CircuitBreaker
+
+		// DependencyTLSConfig may be set to configure TLS for `https` (and `grpc`) dependencies, e.g. a private CA or
+		// client certificate, see kubestatus.LoadClientTLSConfig, note that `grpc` dependencies use plaintext unless
+		// this is set
+		DependencyTLSConfig *tls.Config
+
+		// DependencyHeader may be set to add static headers (or gRPC metadata) to every dependency request
+		DependencyHeader http.Header
+
+		// DependencyCredentials may be set to add (possibly rotating) headers (or gRPC metadata) to every dependency
+		// request, e.g. kubestatus.BearerTokenFile, they are only sent over TLS, unless DependencyInsecureCredentials
+		DependencyCredentials CredentialsProvider
+
+		// DependencyInsecureCredentials, if true, will send DependencyCredentials to dependencies without TLS
+		DependencyInsecureCredentials bool
+
+		// Webhook may be configured to notify webhooks of any change in the health or readiness of the service
+		Webhook WebhookConfig
+
+		// Pod may be set to identify the Kubernetes pod running the service, which is included in every Status,
+		// and therefore the errors of any dependants, see kubestatus.LoadPodInfo
+		Pod PodInfo
+
+		// Build may be set to provide build info, any non-empty fields override those read from the binary (via
+		// runtime/debug.ReadBuildInfo), e.g. to set the Version of the service
+		Build BuildInfo
+
+		// StatusBuild, if true, will include the build info in every Status
+		StatusBuild bool
+
+		// VersionPath may be set to serve the build info for this service, e.g. `/version`
+		VersionPath string
+
+		// HistorySize is the number of evaluations retained per endpoint, and per check, see
+		// kubestatus.Service.History, a zero value disables history
+		HistorySize int
+
+		// HistoryPath may be set to serve the history for this service, e.g. `/history`
+		HistoryPath string
+
+		// MetricsPath may be set to serve prometheus metrics for this service, e.g. `/metrics`, note that the
+		// collector is always available via kubestatus.Service.Collector
+		MetricsPath string
+
+		// TracerProvider is used to create spans for each endpoint, check and dependency, defaults to the global
+		// provider
+		TracerProvider trace.TracerProvider
+
+		// Propagator is used to extract and inject trace context, including for dependencies, defaults to W3C trace
+		// context
+		Propagator propagation.TextMapPropagator
+
+		// Redaction configures how error messages are sanitized before they are exposed, by default URL userinfo
+		// and secret-like key=value pairs are redacted, see kubestatus.RedactionPolicy
+		Redaction RedactionPolicy
+
+		// TLSCertFile and TLSKeyFile may be set to serve over TLS, the files are reloaded whenever they change, so
+		// rotated certificates are picked up without a restart
+		TLSCertFile string
+		TLSKeyFile  string
+
+		// TLSConfig may be set to serve over TLS, any TLSCertFile and TLSClientCAFile take precedence over the
+		// equivalent fields
+		TLSConfig *tls.Config
+
+		// TLSClientCAFile may be set to verify client certificates (mTLS) signed by the PEM encoded CA(s), if they are
+		// presented, so that callers without certificates (e.g. the kubelet) may still receive the bare status, see
+		// Config.Authorizer and kubestatus.ClientCertificateAuthorizer
+		TLSClientCAFile string
+
+		// TLSClientCertRequired, if true, will require client certificates, rather than only verifying them if they
+		// are presented, note that this will break probes made by the kubelet, which doesn't present certificates
+		TLSClientCertRequired bool
+
+		// Authorizer may be set to restrict detailed status (error messages, checks, the history, metrics and the
+		// version) to authorized callers, where unauthorized callers (e.g. the kubelet) still receive the status code,
+		// with a bare body, but are denied the history, metrics and version, and may not exclude or select checks, see
+		// kubestatus.BearerTokenAuthorizer and kubestatus.ClientCertificateAuthorizer
+		Authorizer Authorizer
+
 		// UUID may be set to override the UUID used for this service, a zero value will auto-generate.
 		UUID [16]byte
+
+		// WorkerFatal, if true, will cause the first failed worker (see kubestatus.Service.Go) to be recorded as the
+		// service's FatalError, and cancel the service's context, otherwise failures only affect `/healthz`
+		WorkerFatal bool
 	}
 )
 
@@ -73,37 +236,183 @@ func NewConfig() Config {
 		Port:      DefaultPort,
 		StartWait: DefaultStartWait,
 		GinHandlers: []gin.HandlerFunc{
-			gin.Logger(),
 			gin.Recovery(),
 		},
+		HealthPath:    DefaultHealthPath,
+		ReadinessPath: DefaultReadinessPath,
+		Logger:        slog.Default(),
 	}
 }
 
 // Validate returns an error if config is invalid
 func (c Config) Validate() error {
-	if c.Port <= 0 {
+	if len(c.Listeners) == 0 && c.Port <= 0 {
 		return fmt.Errorf("invalid port: %v", c.Port)
 	}
+	if err := validateListeners(c.Listeners); err != nil {
+		return fmt.Errorf("invalid Listeners: %s", err.Error())
+	}
+	if !c.servesAllRoutes() {
+		for _, path := range []string{c.VersionPath, c.HistoryPath, c.MetricsPath} {
+			if path != "" {
+				return fmt.Errorf("path %q requires a listener serving all routes (with empty Endpoints)", path)
+			}
+		}
+	}
 	if c.StartWait < 0 {
 		return fmt.Errorf("invalid start wait: %v", c.StartWait)
 	}
+	if c.HistorySize < 0 {
+		return fmt.Errorf("invalid history size: %v", c.HistorySize)
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		return errors.New("TLSCertFile and TLSKeyFile must be set together")
+	}
+	if c.TLSClientCAFile != "" && !c.TLS() {
+		return errors.New("TLSClientCAFile requires TLS")
+	}
+	if c.TLSClientCertRequired && c.TLSClientCAFile == "" {
+		return errors.New("TLSClientCertRequired requires TLSClientCAFile")
+	}
 	if c.HealthHandler == nil {
 		return errors.New("nil HealthHandler")
 	}
 	if c.ReadinessHandler == nil {
 		return errors.New("nil ReadinessHandler")
 	}
+	if err := c.validateRoutes(); err != nil {
+		return err
+	}
+	for _, dependency := range c.Dependencies {
+		if !isGRPCDependency(dependency) {
+			continue
+		}
+		if _, err := parseGRPCDependency(dependency); err != nil {
+			return fmt.Errorf("invalid dependency %q: %s", dependency, err.Error())
+		}
+	}
+	if err := validateChecks(c.HealthChecks); err != nil {
+		return fmt.Errorf("invalid HealthChecks: %s", err.Error())
+	}
+	if err := validateChecks(c.ReadinessChecks); err != nil {
+		return fmt.Errorf("invalid ReadinessChecks: %s", err.Error())
+	}
+	return nil
+}
+
+// paths returns the route paths (without the prefix) of an endpoint, the primary path first, then any aliases
+func (c Config) paths(endpoint Endpoint) []string {
+	switch endpoint {
+	case EndpointHealth:
+		path := c.HealthPath
+		if path == "" {
+			path = DefaultHealthPath
+		}
+		return append([]string{path}, c.HealthAliases...)
+	case EndpointReadiness:
+		path := c.ReadinessPath
+		if path == "" {
+			path = DefaultReadinessPath
+		}
+		return append([]string{path}, c.ReadinessAliases...)
+	default:
+		return nil
+	}
+}
+
+func (c Config) dependencyPath() string {
+	if c.DependencyPath == "" {
+		return DefaultReadinessPath
+	}
+	return c.DependencyPath
+}
+
+// validateRoutes ensures all route paths are valid and unique, and that no path is shadowed by the `/:check` route
+// of an endpoint, as the router would otherwise panic, or serve the wrong route
+func (c Config) validateRoutes() error {
+	if c.PathPrefix != "" && (!strings.HasPrefix(c.PathPrefix, "/") || strings.HasSuffix(c.PathPrefix, "/") ||
+		strings.ContainsAny(c.PathPrefix, ":*")) {
+		return fmt.Errorf("invalid path prefix: %q", c.PathPrefix)
+	}
+	if c.DependencyPath != "" && !strings.HasPrefix(c.DependencyPath, "/") {
+		return fmt.Errorf("invalid dependency path: %q", c.DependencyPath)
+	}
+	var paths, checkPaths []string
+	for _, endpoint := range []Endpoint{EndpointHealth, EndpointReadiness} {
+		paths = append(paths, c.paths(endpoint)...)
+		checkPaths = append(checkPaths, c.paths(endpoint)...)
+	}
+	for _, path := range []string{c.VersionPath, c.HistoryPath, c.MetricsPath} {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	seen := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		if !strings.HasPrefix(path, "/") || path == "/" || strings.HasSuffix(path, "/") ||
+			strings.ContainsAny(path, ":*") {
+			return fmt.Errorf("invalid path: %q", path)
+		}
+		if _, ok := seen[path]; ok {
+			return fmt.Errorf("duplicate path: %q", path)
+		}
+		seen[path] = struct{}{}
+	}
+	for _, path := range paths {
+		for _, checkPath := range checkPaths {
+			if strings.HasPrefix(path, checkPath+"/") {
+				return fmt.Errorf("conflicting path: %q is shadowed by %q", path, checkPath+"/:check")
+			}
+		}
+	}
 	return nil
 }
 
-// URL returns the HTTP url this service will bind on (an empty host defaults to localhost), it has a http scheme
+func validateChecks(checks []Check) error {
+	names := make(map[string]struct{}, len(checks))
+	for i, check := range checks {
+		if check.Name == "" {
+			return fmt.Errorf("empty name at index %d", i)
+		}
+		if check.Name == HandlerCheckName {
+			return fmt.Errorf("reserved name %q", check.Name)
+		}
+		if check.Handler == nil {
+			return fmt.Errorf("nil handler for %q", check.Name)
+		}
+		if _, ok := names[check.Name]; ok {
+			return fmt.Errorf("duplicate name %q", check.Name)
+		}
+		names[check.Name] = struct{}{}
+	}
+	return nil
+}
+
+// URL returns the url this service will bind on (an empty host defaults to localhost), which has a https scheme if
+// TLS is enabled (see Config.TLS), otherwise a http scheme, if Listeners is set it uses the first tcp listener, or
+// returns an empty string if there are only unix sockets
 func (c Config) URL() string {
 	URL := new(url.URL)
 	URL.Scheme = "http"
-	hostname := c.Hostname
+	if c.TLS() {
+		URL.Scheme = "https"
+	}
+	hostname, port := c.Hostname, c.Port
+	if len(c.Listeners) != 0 {
+		port = 0
+		for _, listener := range c.Listeners {
+			if listener.UnixSocket == "" {
+				hostname, port = listener.Hostname, listener.Port
+				break
+			}
+		}
+		if port == 0 {
+			return ""
+		}
+	}
 	if hostname == "" {
 		hostname = "localhost"
 	}
-	URL.Host = fmt.Sprintf("%s:%d", hostname, c.Port)
+	URL.Host = fmt.Sprintf("%s:%d", hostname, port)
 	return URL.String()
 }
@@ -0,0 +1,157 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestConfig_Validate_routes(t *testing.T) {
+	for _, testCase := range []struct {
+		Modify func(config *Config)
+		Error  string
+	}{
+		{func(config *Config) {}, ""},
+		{func(config *Config) { config.HealthPath, config.ReadinessPath = "/livez", "/readyz" }, ""},
+		{func(config *Config) { config.HealthPath, config.ReadinessPath = "/health", "/health/ready" }, "conflicting path"},
+		{func(config *Config) { config.ReadinessAliases = []string{"/readyz", "/healthz/ready"} }, "conflicting path"},
+		{func(config *Config) { config.HealthPath, config.MetricsPath = "/status", "/status/metrics" }, "conflicting path"},
+		{func(config *Config) { config.ReadinessAliases = []string{"/readyz", "/health/ready"} }, ""},
+		{func(config *Config) { config.ReadinessAliases = []string{"/ready:z"} }, "invalid path"},
+		{func(config *Config) { config.HealthPath = "/health/*all" }, "invalid path"},
+		{func(config *Config) { config.PathPrefix = "/:prefix" }, "invalid path prefix"},
+		{func(config *Config) { config.PathPrefix = "/status" }, ""},
+		{func(config *Config) { config.PathPrefix = "status" }, "invalid path prefix"},
+		{func(config *Config) { config.PathPrefix = "/status/" }, "invalid path prefix"},
+		{func(config *Config) { config.HealthPath = "healthz" }, "invalid path"},
+		{func(config *Config) { config.ReadinessAliases = []string{"/"} }, "invalid path"},
+		{func(config *Config) { config.ReadinessAliases = []string{"/readyz/"} }, "invalid path"},
+		{func(config *Config) { config.ReadinessPath = "/healthz" }, "duplicate path"},
+		{func(config *Config) { config.HealthAliases = []string{"/livez", "/livez"} }, "duplicate path"},
+		{func(config *Config) { config.HistoryPath, config.MetricsPath = "/history", "/history" }, "duplicate path"},
+		{func(config *Config) { config.DependencyPath = "readyz" }, "invalid dependency path"},
+	} {
+		config := NewConfig()
+		config.HealthHandler = func() error { return nil }
+		config.ReadinessHandler = func() error { return nil }
+		testCase.Modify(&config)
+		err := config.Validate()
+		if testCase.Error == "" {
+			if err != nil {
+				t.Error(err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), testCase.Error) {
+			t.Error(testCase.Error, err)
+		}
+	}
+}
+
+func TestService_routes(t *testing.T) {
+	config := NewConfig()
+	config.Logger = nil
+	config.HistorySize = DefaultHistorySize
+	config.HistoryPath = DefaultHistoryPath
+	config.VersionPath = DefaultVersionPath
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.HealthPath = "/health"
+	config.HealthAliases = []string{"/livez"}
+	config.ReadinessPath = "/ready"
+	config.ReadinessAliases = []string{"/readyz"}
+	config.PathPrefix = "/status"
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pretend we started
+	func() {
+		service.mutex.Lock()
+		defer service.mutex.Unlock()
+		service.fatal = FatalError{}
+	}()
+
+	for _, testCase := range []struct {
+		Target string
+		Code   int
+	}{
+		{"/status/health", 200},
+		{"/status/livez", 200},
+		{"/status/health/handler", 200},
+		{"/status/livez/handler", 200},
+		{"/status/ready", 200},
+		{"/status/readyz", 200},
+		{"/status/ready/handler", 200},
+		{"/status/health/unknown", 404},
+		{"/status/version", 200},
+		{"/status/history", 200},
+		{"/healthz", 404},
+		{"/readiness", 404},
+		{"/health", 404},
+	} {
+		w := httptest.NewRecorder()
+		service.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, testCase.Target, nil))
+		if w.Code != testCase.Code {
+			t.Error(testCase.Target, w.Code, w.Body.String())
+		}
+	}
+
+	server := httptest.NewServer(service.engine)
+	defer server.Close()
+
+	client := Client{
+		Addresses:     []string{server.URL + "/status"},
+		HealthPath:    "/livez",
+		ReadinessPath: "/ready",
+	}
+	if _, err := client.Health(); err != nil {
+		t.Error(err)
+	}
+	if _, err := client.Readiness(); err != nil {
+		t.Error(err)
+	}
+	if _, err := (Client{Addresses: []string{server.URL}}).Health(); err == nil {
+		t.Error("expected an error")
+	}
+
+	// dependencies may use a different convention
+	dependant := NewConfig()
+	dependant.Logger = nil
+	dependant.HealthHandler = config.HealthHandler
+	dependant.ReadinessHandler = config.ReadinessHandler
+	dependant.Dependencies = []string{server.URL + "/status"}
+	dependant.DependencyPath = "/readyz"
+	dependantService, err := NewService(dependant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	func() {
+		dependantService.mutex.Lock()
+		defer dependantService.mutex.Unlock()
+		dependantService.fatal = FatalError{}
+	}()
+	if status := dependantService.Readiness(); !status.Success {
+		t.Error(status)
+	}
+}
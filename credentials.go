@@ -0,0 +1,127 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"net/http"
+	"strings"
+)
+
+// CredentialsProvider returns headers (e.g. Authorization) to add to each request to an address, it's called for
+// every request, so may return rotated credentials, see Client.Credentials
+type CredentialsProvider func(ctx context.Context, address string) (http.Header, error)
+
+// BearerToken returns a CredentialsProvider that authenticates every request with a static bearer token
+func BearerToken(token string) CredentialsProvider {
+	return func(ctx context.Context, address string) (http.Header, error) {
+		return http.Header{"Authorization": []string{"Bearer " + token}}, nil
+	}
+}
+
+// BearerTokenFile returns a CredentialsProvider that authenticates every request with a bearer token read from a
+// file on each request, e.g. a projected Kubernetes service account token, which is rotated by the kubelet
+func BearerTokenFile(path string) CredentialsProvider {
+	return func(ctx context.Context, address string) (http.Header, error) {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		token := strings.TrimSpace(string(b))
+		if token == "" {
+			return nil, fmt.Errorf("empty bearer token file %q", path)
+		}
+		return http.Header{"Authorization": []string{"Bearer " + token}}, nil
+	}
+}
+
+// LoadClientTLSConfig builds a tls config for a Client (or Config.DependencyTLSConfig), where caFile may be set to
+// trust a private CA bundle (instead of the system roots), certFile and keyFile may be set to present a client
+// certificate (mTLS), and serverName may be set to override the name used to verify the server's certificate
+func LoadClientTLSConfig(caFile, certFile, keyFile, serverName string) (*tls.Config, error) {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+	}
+
+	if caFile != "" {
+		b, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("kubestatus.LoadClientTLSConfig failed to load CA: %s", err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("kubestatus.LoadClientTLSConfig no certificates found in CA %q", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("kubestatus.LoadClientTLSConfig certFile and keyFile must be set together")
+	}
+
+	if certFile != "" {
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("kubestatus.LoadClientTLSConfig failed to load certificate: %s", err.Error())
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, nil
+}
+
+// newTLSClient returns a http client with its own transport, using the tls config
+func newTLSClient(config *tls.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config
+	return &http.Client{Transport: transport}
+}
+
+// header returns the headers for a request to the address, combining Header and Credentials, where secure indicates
+// if the request will be made over TLS, as credentials are otherwise refused, see Client.AllowInsecureCredentials
+func (c Client) header(ctx context.Context, address string, secure bool) (http.Header, error) {
+	header := c.Header.Clone()
+	if c.Credentials != nil {
+		if !secure && !c.AllowInsecureCredentials {
+			return nil, errors.New("kubestatus.Client credentials require TLS, see Client.AllowInsecureCredentials")
+		}
+		credentials, err := c.Credentials(ctx, address)
+		if err != nil {
+			return nil, fmt.Errorf("kubestatus.Client credentials failed: %s", err.Error())
+		}
+		if header == nil {
+			header = make(http.Header, len(credentials))
+		}
+		for key, values := range credentials {
+			header[http.CanonicalHeaderKey(key)] = values
+		}
+	}
+	return header, nil
+}
+
+func (c Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,261 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBearerTokenFile(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kubestatus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "token")
+
+	provider := BearerTokenFile(file)
+	if _, err := provider(context.Background(), ""); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, token := range []string{"one", "two\n"} {
+		if err := os.WriteFile(file, []byte(token), 0600); err != nil {
+			t.Fatal(err)
+		}
+		header, err := provider(context.Background(), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := header.Get("Authorization"); v != "Bearer "+strings.TrimSpace(token) {
+			t.Fatal(v)
+		}
+	}
+
+	if err := os.WriteFile(file, []byte(" "), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider(context.Background(), ""); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestClient_Get_tls(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Tenant") != "a" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(Status{Code: http.StatusUnauthorized, Message: "unauthorized"})
+			return
+		}
+		json.NewEncoder(w).Encode(Status{Success: true, Code: http.StatusOK})
+	}))
+	defer server.Close()
+
+	dir, err := os.MkdirTemp("", "kubestatus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tlsConfig, err := LoadClientTLSConfig(caFile, "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the system roots don't trust the test server
+	if _, err := (Client{Addresses: []string{server.URL}}).Health(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, testCase := range []struct {
+		Client Client
+		Error  string
+	}{
+		{Client{TLSConfig: tlsConfig}, "401 Unauthorized: unauthorized"},
+		{Client{TLSConfig: tlsConfig, Credentials: BearerToken("secret")}, "401 Unauthorized"},
+		{Client{TLSConfig: tlsConfig, Header: http.Header{"X-Tenant": []string{"a"}, "Authorization": []string{"Bearer wrong"}}, Credentials: BearerToken("secret")}, ""},
+		{Client{TLSConfig: tlsConfig, Header: http.Header{"X-Tenant": []string{"a"}}, Credentials: func(ctx context.Context, address string) (http.Header, error) {
+			return nil, os.ErrPermission
+		}}, "credentials failed"},
+		{Client{HTTPClient: server.Client(), Header: http.Header{"X-Tenant": []string{"a"}}, Credentials: BearerToken("secret")}, ""},
+	} {
+		testCase.Client.Addresses = []string{server.URL}
+		_, err := testCase.Client.Health()
+		if testCase.Error == "" {
+			if err != nil {
+				t.Error(err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), testCase.Error) {
+			t.Error(testCase.Error, err)
+		}
+	}
+}
+
+func TestClient_Get_insecureCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(Status{Success: true, Code: http.StatusOK})
+	}))
+	defer server.Close()
+
+	// credentials aren't sent in plaintext by default
+	if _, err := (Client{Addresses: []string{server.URL}, Credentials: BearerToken("secret")}).Health(); err == nil ||
+		!strings.Contains(err.Error(), "credentials require TLS") {
+		t.Fatal(err)
+	}
+
+	if _, err := (Client{Addresses: []string{server.URL}, Credentials: BearerToken("secret"), AllowInsecureCredentials: true}).Health(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClient_Get_mtls(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kubestatus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	serverCert, serverKey := generateCertificate(t, "server", time.Now().Add(time.Hour))
+	clientCert, clientKey := generateCertificate(t, "client", time.Now().Add(time.Hour))
+	files := map[string][]byte{
+		"ca.pem":   serverCert,
+		"cert.pem": clientCert,
+		"key.pem":  clientKey,
+	}
+	for name, b := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	serverPair, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AppendCertsFromPEM(clientCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Status{Success: true, Code: http.StatusOK, Message: r.TLS.PeerCertificates[0].Subject.CommonName})
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverPair},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	// server name is verified against the certificate, which is valid for localhost
+	address := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+
+	withoutCert, err := LoadClientTLSConfig(filepath.Join(dir, "ca.pem"), "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (Client{Addresses: []string{address}, TLSConfig: withoutCert}).Health(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	withCert, err := LoadClientTLSConfig(filepath.Join(dir, "ca.pem"), filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := (Client{Addresses: []string{address}, TLSConfig: withCert}).Health()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[0].Message != "client" {
+		t.Fatal(statuses[0])
+	}
+
+	wrongName, err := LoadClientTLSConfig(filepath.Join(dir, "ca.pem"), filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (Client{Addresses: []string{address}, TLSConfig: wrongName}).Health(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if _, err := LoadClientTLSConfig("", filepath.Join(dir, "cert.pem"), "", ""); err == nil {
+		t.Fatal("expected an error")
+	}
+	if _, err := LoadClientTLSConfig(filepath.Join(dir, "key.pem"), "", "", ""); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestService_dependencyCredentials(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(Status{Success: true, Code: http.StatusOK})
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	for _, testCase := range []struct {
+		Credentials CredentialsProvider
+		Success     bool
+	}{
+		{nil, false},
+		{BearerToken("secret"), true},
+	} {
+		config := NewConfig()
+		config.Logger = nil
+		config.HealthHandler = func() error { return nil }
+		config.ReadinessHandler = func() error { return nil }
+		config.Dependencies = []string{server.URL}
+		config.DependencyTLSConfig = &tls.Config{RootCAs: roots}
+		config.DependencyCredentials = testCase.Credentials
+		service, err := NewService(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		func() {
+			service.mutex.Lock()
+			defer service.mutex.Unlock()
+			service.fatal = FatalError{}
+		}()
+		if status := service.Readiness(); status.Success != testCase.Success {
+			t.Error(status)
+		}
+	}
+}
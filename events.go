@@ -0,0 +1,183 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// StateUnknown indicates an endpoint or check that has not been evaluated
+	StateUnknown State = iota
+	// StateOK indicates an endpoint or check that was successful
+	StateOK
+	// StateFailing indicates an endpoint or check that failed
+	StateFailing
+
+	// DefaultEventBuffer is the number of events that may be queued per subscriber, before events are dropped
+	DefaultEventBuffer = 64
+)
+
+type (
+	// State is the state of an endpoint or check, as of it's last evaluation
+	State int
+
+	// Event models a change in the state of an endpoint (empty Check), or a check within an endpoint
+	Event struct {
+		// Endpoint is the endpoint that was evaluated
+		Endpoint Endpoint
+
+		// Check is the name of the check that changed, or empty if the endpoint changed as a whole
+		Check string
+
+		// Old is the previous state
+		Old State
+
+		// New is the current state
+		New State
+
+		// Error is the failure, for the StateFailing state
+		Error error
+
+		// Time is when the change was observed
+		Time time.Time
+	}
+
+	// stateKey identifies either an endpoint (empty check), or a check within an endpoint
+	stateKey struct {
+		endpoint Endpoint
+		check    string
+	}
+
+	subscriber struct {
+		fn     func(Event)
+		events chan Event
+		done   chan struct{}
+	}
+)
+
+// String implements fmt.Stringer
+func (s State) String() string {
+	switch s {
+	case StateOK:
+		return "ok"
+	case StateFailing:
+		return "failing"
+	default:
+		return "unknown"
+	}
+}
+
+// Subscribe registers fn to be called with every state transition of the service's endpoints and checks, which are
+// observed whenever they are evaluated (e.g. on each probe), fn is called from a dedicated goroutine, in order, and
+// events will be dropped rather than block evaluation, if more than DefaultEventBuffer are pending, the returned func
+// will unsubscribe, and may be called more than once
+func (s *Service) Subscribe(fn func(Event)) (unsubscribe func()) {
+	s.ensure()
+	if fn == nil {
+		panic(errors.New("kubestatus.Service.Subscribe nil fn"))
+	}
+
+	sub := &subscriber{
+		fn:     fn,
+		events: make(chan Event, DefaultEventBuffer),
+		done:   make(chan struct{}),
+	}
+
+	func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if s.subscribers == nil {
+			s.subscribers = make(map[*subscriber]struct{})
+		}
+		s.subscribers[sub] = struct{}{}
+	}()
+
+	go sub.run()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			delete(s.subscribers, sub)
+			close(sub.done)
+		})
+	}
+}
+
+func (s *subscriber) run() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case event := <-s.events:
+			select {
+			case <-s.done:
+				return
+			default:
+			}
+			s.fn(event)
+		}
+	}
+}
+
+// transition records the latest state of an endpoint or check, publishing an event if it changed, status should be
+// provided for endpoints
+func (s *Service) transition(ctx context.Context, key stateKey, err error, duration time.Duration, status *Status) {
+	event := Event{
+		Endpoint: key.endpoint,
+		Check:    key.check,
+		New:      StateOK,
+		Error:    err,
+		Time:     time.Now(),
+	}
+	if err != nil {
+		event.New = StateFailing
+	}
+
+	var changed bool
+	func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		if s.states == nil {
+			s.states = make(map[stateKey]State)
+		}
+		event.Old = s.states[key]
+		s.states[key] = event.New
+		if changed = event.Old != event.New; !changed {
+			return
+		}
+		for sub := range s.subscribers {
+			select {
+			case sub.events <- event:
+			default:
+			}
+		}
+	}()
+
+	s.record(key, event, duration, status)
+
+	if !changed {
+		return
+	}
+
+	s.logTransition(ctx, event, duration)
+}
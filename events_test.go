@@ -0,0 +1,104 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+	"github.com/gin-gonic/gin"
+)
+
+func TestService_Subscribe(t *testing.T) {
+	var ready int32
+	config := NewConfig()
+	config.Port = 9068
+	config.GinHandlers = nil
+	config.Logger = nil
+	gin.SetMode(gin.ReleaseMode)
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.ReadinessChecks = []Check{
+		{
+			Name: "queue",
+			Handler: func() error {
+				if atomic.LoadInt32(&ready) == 0 {
+					return errors.New("disconnected")
+				}
+				return nil
+			},
+		},
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan Event, 100)
+	unsubscribe := service.Subscribe(func(event Event) {
+		events <- event
+	})
+	defer unsubscribe()
+
+	next := func() Event {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("expected an event")
+		}
+		panic("unreachable")
+	}
+
+	service.Readiness()
+	service.Readiness()
+	atomic.StoreInt32(&ready, 1)
+	service.Readiness()
+
+	for _, expected := range []Event{
+		{Endpoint: EndpointReadiness, Check: HandlerCheckName, Old: StateUnknown, New: StateOK},
+		{Endpoint: EndpointReadiness, Check: "queue", Old: StateUnknown, New: StateFailing},
+		{Endpoint: EndpointReadiness, Old: StateUnknown, New: StateFailing},
+		{Endpoint: EndpointReadiness, Check: "queue", Old: StateFailing, New: StateOK},
+		{Endpoint: EndpointReadiness, Old: StateFailing, New: StateOK},
+	} {
+		event := next()
+		if event.Endpoint != expected.Endpoint || event.Check != expected.Check ||
+			event.Old != expected.Old || event.New != expected.New ||
+			(event.Error != nil) != (event.New == StateFailing) || event.Time.IsZero() {
+			t.Errorf("expected %+v\nactual %+v", expected, event)
+		}
+	}
+
+	unsubscribe()
+	atomic.StoreInt32(&ready, 0)
+	service.Readiness()
+	select {
+	case event := <-events:
+		t.Error("unexpected event", event)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
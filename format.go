@@ -0,0 +1,151 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	"github.com/gin-gonic/gin"
+)
+
+// MIMEHealthJSON is the media type of the IETF health check response format (draft-inadarei-api-health-check)
+const MIMEHealthJSON = "application/health+json"
+
+type (
+	// HealthResponse models the IETF health check response format (draft-inadarei-api-health-check), which is
+	// served for `Accept: application/health+json`
+	HealthResponse struct {
+		// Status is one of `pass` or `fail`
+		Status string `json:"status"`
+
+		// Version is the public version of the service
+		Version string `json:"version,omitempty"`
+
+		// ReleaseID is the VCS revision of the service
+		ReleaseID string `json:"releaseId,omitempty"`
+
+		// ServiceID is the service's UUID
+		ServiceID string `json:"serviceId"`
+
+		// Output is the error message, for the `fail` status
+		Output string `json:"output,omitempty"`
+
+		// Checks are the results of each check, keyed by check name or dependency address
+		Checks map[string][]HealthCheckResponse `json:"checks,omitempty"`
+	}
+
+	// HealthCheckResponse models a single check within a HealthResponse
+	HealthCheckResponse struct {
+		// ComponentType is `component` for checks, or `system` for dependencies
+		ComponentType string `json:"componentType"`
+
+		// Status is one of `pass` or `fail`
+		Status string `json:"status"`
+
+		// Output is the error message, for the `fail` status
+		Output string `json:"output,omitempty"`
+
+		// Time is when the check was evaluated, in RFC3339 format
+		Time string `json:"time"`
+	}
+)
+
+// negotiate returns the preferred response format, which defaults to JSON
+func negotiate(i *gin.Context) string {
+	return i.NegotiateFormat(gin.MIMEJSON, MIMEHealthJSON, gin.MIMEPlain)
+}
+
+// render writes status (and any results) in the negotiated format
+func (s *Service) render(i *gin.Context, format string, status Status, results []CheckResult) {
+	setHeaders(i, status)
+	switch format {
+	case gin.MIMEPlain:
+		if status.Success {
+			i.String(status.Code, "ok\n")
+		} else {
+			i.String(status.Code, "%s\n", status.Message)
+		}
+	case MIMEHealthJSON:
+		i.Render(status.Code, healthJSON{s.healthResponse(status, results)})
+	default:
+		i.JSON(status.Code, status)
+	}
+}
+
+// setHeaders sets any response headers derived from status, i.e. Retry-After
+func setHeaders(i *gin.Context, status Status) {
+	if status.RetryAfter > 0 {
+		i.Header("Retry-After", strconv.FormatInt(status.RetryAfter, 10))
+	}
+}
+
+func (s *Service) healthResponse(status Status, results []CheckResult) HealthResponse {
+	resp := HealthResponse{
+		Status:    healthStatus(status.Success),
+		Version:   s.build.Version,
+		ReleaseID: s.build.Revision,
+		ServiceID: status.UUID,
+	}
+	if !status.Success {
+		resp.Output = status.Message
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	for _, result := range results {
+		if result.Excluded {
+			continue
+		}
+		check := HealthCheckResponse{
+			ComponentType: "component",
+			Status:        healthStatus(result.Success),
+			Time:          now,
+		}
+		if result.dependency {
+			check.ComponentType = "system"
+		}
+		if !result.Success {
+			check.Output = result.Message
+		}
+		if resp.Checks == nil {
+			resp.Checks = make(map[string][]HealthCheckResponse)
+		}
+		resp.Checks[result.Name] = append(resp.Checks[result.Name], check)
+	}
+	return resp
+}
+
+func healthStatus(success bool) string {
+	if success {
+		return "pass"
+	}
+	return "fail"
+}
+
+// healthJSON renders JSON with the MIMEHealthJSON content type
+type healthJSON struct {
+	value interface{}
+}
+
+func (r healthJSON) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	return json.NewEncoder(w).Encode(r.value)
+}
+
+func (r healthJSON) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", MIMEHealthJSON)
+}
@@ -0,0 +1,117 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"github.com/google/uuid"
+)
+
+func TestService_contentNegotiation(t *testing.T) {
+	config := NewConfig()
+	config.Logger = nil
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.ReadinessChecks = []Check{
+		{
+			Name: "database",
+			Handler: func() error {
+				return errors.New("not connected")
+			},
+		},
+		{
+			Name: "cache",
+			Handler: func() error {
+				return nil
+			},
+		},
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pretend we started
+	func() {
+		service.mutex.Lock()
+		defer service.mutex.Unlock()
+		service.fatal = FatalError{}
+	}()
+
+	get := func(target string, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		service.engine.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, testCase := range []struct {
+		Target      string
+		Accept      string
+		Code        int
+		ContentType string
+		Body        string
+	}{
+		{"/healthz", "", 200, "application/json", ""},
+		{"/healthz", "*/*", 200, "application/json", ""},
+		{"/healthz", "text/plain", 200, "text/plain", "ok\n"},
+		{"/readiness", "text/plain", 503, "text/plain", "database: not connected\n"},
+		{"/readiness/cache", "text/plain", 200, "text/plain", "ok\n"},
+		{"/readiness", "text/html, text/plain;q=0.9", 503, "text/plain", "database: not connected\n"},
+		{"/readiness", "application/health+json", 503, MIMEHealthJSON, ""},
+		{"/readiness?verbose", "application/health+json", 503, MIMEHealthJSON, ""},
+		{"/readiness?verbose", "text/plain", 503, "text/plain", ""},
+	} {
+		w := get(testCase.Target, testCase.Accept)
+		if w.Code != testCase.Code || !strings.HasPrefix(w.Header().Get("Content-Type"), testCase.ContentType) ||
+			(testCase.Body != "" && w.Body.String() != testCase.Body) {
+			t.Error(testCase, w.Code, w.Header().Get("Content-Type"), w.Body.String())
+		}
+	}
+
+	var resp HealthResponse
+	if err := json.Unmarshal(get("/readiness", MIMEHealthJSON).Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != "fail" || resp.ServiceID != uuid.UUID(service.UUID()).String() ||
+		resp.Output != "database: not connected" || len(resp.Checks) != 3 ||
+		len(resp.Checks["database"]) != 1 || resp.Checks["database"][0].Status != "fail" ||
+		resp.Checks["database"][0].Output != "not connected" || resp.Checks["cache"][0].Status != "pass" ||
+		resp.Checks["handler"][0].ComponentType != "component" {
+		t.Errorf("%+v", resp)
+	}
+
+	var verbose HealthResponse
+	if err := json.Unmarshal(get("/readiness?verbose", MIMEHealthJSON).Body.Bytes(), &verbose); err != nil {
+		t.Fatal(err)
+	}
+	if verbose.Status != resp.Status || len(verbose.Checks) != len(resp.Checks) {
+		t.Errorf("%+v", verbose)
+	}
+}
@@ -0,0 +1,284 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+const (
+	DefaultGRPCWatchInterval     = time.Second
+	DefaultGRPCDependencyTimeout = time.Second * 5
+
+	// GRPCUUIDsMetadata is the gRPC metadata key used to pass the traversed UUIDs for grpc dependencies, the
+	// equivalent of the `uuids` query parameter
+	GRPCUUIDsMetadata = "kubestatus-uuids"
+)
+
+type (
+	// GRPCHealthServer exposes a Service as a grpc.health.v1.Health server, register it using
+	// grpc_health_v1.RegisterHealthServer, note that the empty service name maps to the service's overall readiness,
+	// unless it is overridden via Services
+	GRPCHealthServer struct {
+		healthpb.UnimplementedHealthServer
+
+		// Service provides the health and readiness state
+		Service *Service
+
+		// Services maps gRPC service names to either a whole endpoint or a single named check
+		Services map[string]GRPCHealthCheck
+
+		// WatchInterval is how often the state is polled for Watch, defaults to DefaultGRPCWatchInterval
+		WatchInterval time.Duration
+	}
+
+	grpcDependency struct {
+		target  string
+		service string
+		timeout time.Duration
+	}
+
+	// GRPCHealthCheck identifies the state reported for a gRPC service name
+	GRPCHealthCheck struct {
+		// Endpoint is the endpoint the check belongs to
+		Endpoint Endpoint
+
+		// Name may be set to report a single named check (see Config.HealthChecks and Config.ReadinessChecks), an
+		// empty name reports the endpoint as a whole
+		Name string
+	}
+)
+
+func (g *GRPCHealthServer) check(service string) (GRPCHealthCheck, bool) {
+	if check, ok := g.Services[service]; ok {
+		return check, true
+	}
+	if service == "" {
+		return GRPCHealthCheck{Endpoint: EndpointReadiness}, true
+	}
+	return GRPCHealthCheck{}, false
+}
+
+func (g *GRPCHealthServer) status(ctx context.Context, service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+	if g == nil || g.Service == nil {
+		return healthpb.HealthCheckResponse_UNKNOWN, errors.New("kubestatus.GRPCHealthServer nil service")
+	}
+
+	check, ok := g.check(service)
+	if !ok {
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, nil
+	}
+
+	// continue any trace propagated via the incoming metadata
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		ctx = g.Service.propagator().Extract(ctx, metadataCarrier(md))
+	}
+
+	var status Status
+	switch {
+	case check.Name != "":
+		status, ok = g.Service.Check(check.Endpoint, check.Name)
+		if !ok {
+			return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, nil
+		}
+	case check.Endpoint == EndpointHealth:
+		status = g.Service.HealthContext(ctx)
+	case check.Endpoint == EndpointReadiness:
+		status = g.Service.ReadinessContext(ctx, grpcUUIDs(ctx)...)
+	default:
+		return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, nil
+	}
+
+	if !status.Success {
+		return healthpb.HealthCheckResponse_NOT_SERVING, nil
+	}
+
+	return healthpb.HealthCheckResponse_SERVING, nil
+}
+
+// Check implements grpc_health_v1.HealthServer
+func (g *GRPCHealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	status, err := g.status(ctx, req.GetService())
+	if err != nil {
+		return nil, grpcstatus.Error(codes.Internal, err.Error())
+	}
+	if status == healthpb.HealthCheckResponse_SERVICE_UNKNOWN {
+		return nil, grpcstatus.Errorf(codes.NotFound, "unknown service: %q", req.GetService())
+	}
+	return &healthpb.HealthCheckResponse{Status: status}, nil
+}
+
+// List implements grpc_health_v1.HealthServer, reporting all mapped services (including the empty service name)
+func (g *GRPCHealthServer) List(ctx context.Context, req *healthpb.HealthListRequest) (*healthpb.HealthListResponse, error) {
+	services := []string{""}
+	for service := range g.Services {
+		if service != "" {
+			services = append(services, service)
+		}
+	}
+	resp := &healthpb.HealthListResponse{Statuses: make(map[string]*healthpb.HealthCheckResponse, len(services))}
+	for _, service := range services {
+		status, err := g.status(ctx, service)
+		if err != nil {
+			return nil, grpcstatus.Error(codes.Internal, err.Error())
+		}
+		resp.Statuses[service] = &healthpb.HealthCheckResponse{Status: status}
+	}
+	return resp, nil
+}
+
+// Watch implements grpc_health_v1.HealthServer, sending the current state, then polling and sending any changes,
+// until the stream is closed, unknown services are reported as SERVICE_UNKNOWN
+func (g *GRPCHealthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	interval := g.WatchInterval
+	if interval <= 0 {
+		interval = DefaultGRPCWatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last *healthpb.HealthCheckResponse_ServingStatus
+
+	for {
+		status, err := g.status(stream.Context(), req.GetService())
+		if err != nil {
+			return grpcstatus.Error(codes.Internal, err.Error())
+		}
+
+		if last == nil || *last != status {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: status}); err != nil {
+				return err
+			}
+			last = &status
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return grpcstatus.FromContextError(stream.Context().Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// grpcUUIDs extracts any UUIDs passed in via the incoming metadata (see GRPCUUIDsMetadata)
+func grpcUUIDs(ctx context.Context) []string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	UUIDs := make([]string, 0)
+	for _, value := range md.Get(GRPCUUIDsMetadata) {
+		for _, UUID := range strings.Split(value, ",") {
+			UUID = strings.TrimSpace(UUID)
+			if UUID == "" {
+				continue
+			}
+			UUIDs = append(UUIDs, UUID)
+		}
+	}
+	return UUIDs
+}
+
+func isGRPCDependency(address string) bool {
+	return strings.HasPrefix(strings.ToLower(address), "grpc://")
+}
+
+func parseGRPCDependency(address string) (grpcDependency, error) {
+	URL, err := url.Parse(address)
+	if err != nil {
+		return grpcDependency{}, err
+	}
+	if URL.Host == "" {
+		return grpcDependency{}, errors.New("missing host")
+	}
+	dependency := grpcDependency{
+		target:  URL.Host,
+		service: strings.TrimPrefix(URL.Path, "/"),
+		timeout: DefaultGRPCDependencyTimeout,
+	}
+	if timeout := URL.Query().Get("timeout"); timeout != "" {
+		if dependency.timeout, err = time.ParseDuration(timeout); err != nil {
+			return grpcDependency{}, err
+		}
+		if dependency.timeout <= 0 {
+			return grpcDependency{}, fmt.Errorf("invalid timeout: %s", timeout)
+		}
+	}
+	return dependency, nil
+}
+
+// checkGRPCDependency performs a grpc.health.v1.Health/Check against a `grpc://` dependency, returning an error
+// unless it is SERVING, the client provides the UUIDs, propagator, TLS config and headers (sent as metadata)
+func checkGRPCDependency(ctx context.Context, address string, client Client) error {
+	dependency, err := parseGRPCDependency(address)
+	if err != nil {
+		return fmt.Errorf("invalid grpc dependency %q: %s", address, err.Error())
+	}
+
+	header, err := client.header(ctx, address, client.TLSConfig != nil)
+	if err != nil {
+		return fmt.Errorf("grpc dependency %q: %s", address, err.Error())
+	}
+
+	transportCredentials := insecure.NewCredentials()
+	if client.TLSConfig != nil {
+		transportCredentials = credentials.NewTLS(client.TLSConfig)
+	}
+
+	conn, err := grpc.NewClient(dependency.target, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		return fmt.Errorf("grpc dependency %q: %s", address, err.Error())
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, dependency.timeout)
+	defer cancel()
+
+	md := metadata.MD{}
+	for key, values := range header {
+		md.Set(key, values...)
+	}
+	if len(client.UUIDs) != 0 {
+		md.Set(GRPCUUIDsMetadata, strings.Join(client.UUIDs, ","))
+	}
+	propagator(client.Propagator).Inject(ctx, metadataCarrier(md))
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: dependency.service})
+	if err != nil {
+		if grpcstatus.Code(err) == codes.DeadlineExceeded {
+			err = timeoutError{err}
+		}
+		return fmt.Errorf("grpc dependency %q: %w", address, err)
+	}
+
+	if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("grpc dependency %q: %s", address, resp.GetStatus().String())
+	}
+
+	return nil
+}
@@ -0,0 +1,260 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startGRPCHealthServer serves a health server over an in-memory listener, returning a connected client
+func startGRPCHealthServer(t *testing.T, server healthpb.HealthServer) healthpb.HealthClient {
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestGRPCHealthServer(t *testing.T) {
+	var ready int32
+	config := NewConfig()
+	config.Port = 9062
+	config.GinHandlers = nil
+	gin.SetMode(gin.ReleaseMode)
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.ReadinessChecks = []Check{
+		{
+			Name: "database",
+			Handler: func() error {
+				if atomic.LoadInt32(&ready) == 0 {
+					return errors.New("not connected")
+				}
+				return nil
+			},
+		},
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := startGRPCHealthServer(t, &GRPCHealthServer{
+		Service: service,
+		Services: map[string]GRPCHealthCheck{
+			"liveness":        {Endpoint: EndpointHealth},
+			"example.Service": {Endpoint: EndpointReadiness, Name: "database"},
+			"missing":         {Endpoint: EndpointReadiness, Name: "missing"},
+		},
+		WatchInterval: time.Millisecond * 10,
+	})
+
+	check := func(service string) (healthpb.HealthCheckResponse_ServingStatus, codes.Code) {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return healthpb.HealthCheckResponse_UNKNOWN, grpcstatus.Code(err)
+		}
+		return resp.GetStatus(), codes.OK
+	}
+
+	// not started yet
+	if status, code := check(""); status != healthpb.HealthCheckResponse_NOT_SERVING || code != codes.OK {
+		t.Error(status, code)
+	}
+
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	watchCtx, watchCancel := context.WithCancel(context.Background())
+	defer watchCancel()
+	watch, err := client.Watch(watchCtx, &healthpb.HealthCheckRequest{Service: "example.Service"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := watch.Recv(); err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatal(resp, err)
+	}
+
+	for _, testCase := range []struct {
+		Service string
+		Status  healthpb.HealthCheckResponse_ServingStatus
+		Code    codes.Code
+	}{
+		{"", healthpb.HealthCheckResponse_NOT_SERVING, codes.OK},
+		{"liveness", healthpb.HealthCheckResponse_SERVING, codes.OK},
+		{"example.Service", healthpb.HealthCheckResponse_NOT_SERVING, codes.OK},
+		{"missing", healthpb.HealthCheckResponse_UNKNOWN, codes.NotFound},
+		{"unknown", healthpb.HealthCheckResponse_UNKNOWN, codes.NotFound},
+	} {
+		if status, code := check(testCase.Service); status != testCase.Status || code != testCase.Code {
+			t.Error(testCase, status, code)
+		}
+	}
+
+	atomic.StoreInt32(&ready, 1)
+
+	if status, code := check(""); status != healthpb.HealthCheckResponse_SERVING || code != codes.OK {
+		t.Error(status, code)
+	}
+	if status, code := check("example.Service"); status != healthpb.HealthCheckResponse_SERVING || code != codes.OK {
+		t.Error(status, code)
+	}
+	if resp, err := watch.Recv(); err != nil || resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+		t.Error(resp, err)
+	}
+
+	list, err := client.List(context.Background(), &healthpb.HealthListRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.GetStatuses()) != 4 || list.GetStatuses()["missing"].GetStatus() != healthpb.HealthCheckResponse_SERVICE_UNKNOWN {
+		t.Error(list)
+	}
+}
+
+func TestService_Readiness_grpcDependency(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dependency := health.NewServer()
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, dependency)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	config := NewConfig()
+	config.Port = 9063
+	config.GinHandlers = nil
+	gin.SetMode(gin.ReleaseMode)
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.Dependencies = []string{"grpc://" + listener.Addr().String() + "/example.Service?timeout=1s"}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if readiness := service.Readiness(); readiness.Success ||
+		!strings.Contains(readiness.Message, "code = NotFound") {
+		t.Error(readiness)
+	}
+
+	dependency.SetServingStatus("example.Service", healthpb.HealthCheckResponse_NOT_SERVING)
+	if readiness := service.Readiness(); readiness.Success ||
+		!strings.HasSuffix(readiness.Message, ": NOT_SERVING") {
+		t.Error(readiness)
+	}
+
+	dependency.SetServingStatus("example.Service", healthpb.HealthCheckResponse_SERVING)
+	if readiness := service.Readiness(); !readiness.Success {
+		t.Error(readiness)
+	}
+}
+
+// slowHealthServer never responds, until the request is cancelled
+type slowHealthServer struct {
+	healthpb.UnimplementedHealthServer
+}
+
+func (slowHealthServer) Check(ctx context.Context, _ *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	<-ctx.Done()
+	return nil, grpcstatus.FromContextError(ctx.Err()).Err()
+}
+
+func TestCheckGRPCDependency_timeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, slowHealthServer{})
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	err = checkGRPCDependency(context.Background(), "grpc://"+listener.Addr().String()+"?timeout=50ms", Client{})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "code = DeadlineExceeded") {
+		t.Fatal(err)
+	}
+	var statusErr *StatusError
+	if !errors.As(withReason(err, ReasonDependencyFailed), &statusErr) || statusErr.Reason != ReasonTimeout {
+		t.Error(statusErr)
+	}
+}
+
+func TestConfig_Validate_grpcDependency(t *testing.T) {
+	config := NewConfig()
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	for _, testCase := range []struct {
+		Dependency string
+		Valid      bool
+	}{
+		{"grpc://localhost:9090", true},
+		{"grpc://localhost:9090/some.Service?timeout=100ms", true},
+		{"grpc:///some.Service", false},
+		{"grpc://localhost:9090?timeout=nope", false},
+		{"grpc://localhost:9090?timeout=-1s", false},
+	} {
+		config.Dependencies = []string{testCase.Dependency}
+		if err := config.Validate(); (err == nil) != testCase.Valid {
+			t.Error(testCase, err)
+		}
+	}
+}
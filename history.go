@@ -0,0 +1,204 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultHistorySize is a suggested value for Config.HistorySize, history is disabled by default
+	DefaultHistorySize = 100
+
+	// DefaultHistoryPath is a suggested value for Config.HistoryPath, which is not served by default
+	DefaultHistoryPath = "/history"
+)
+
+type (
+	// HistoryEntry models a single evaluation of an endpoint or check
+	HistoryEntry struct {
+		// Endpoint is the endpoint that was evaluated
+		Endpoint Endpoint `json:"endpoint"`
+
+		// Check is the name of the check that was evaluated, or empty for the endpoint as a whole
+		Check string `json:"check,omitempty"`
+
+		// Old is the state prior to this evaluation
+		Old string `json:"old"`
+
+		// New is the state as of this evaluation
+		New string `json:"new"`
+
+		// Transition will be true if the state changed
+		Transition bool `json:"transition"`
+
+		// Message will be either 'OK', or the error message
+		Message string `json:"message"`
+
+		// Time is a nanoseconds epoch indicating when the evaluation completed
+		Time int64 `json:"time"`
+
+		// Duration is a human readable string representation of how long the evaluation took
+		Duration string `json:"duration"`
+
+		// Status is the result of evaluating an endpoint, it will be nil for checks
+		Status *Status `json:"status,omitempty"`
+	}
+
+	// HistoryFilter filters the entries returned by Service.History, zero values match all entries
+	HistoryFilter struct {
+		// Endpoint matches entries for an endpoint (including it's checks)
+		Endpoint Endpoint
+
+		// Check matches entries for a single check
+		Check string
+
+		// Since matches entries at or after a point in time
+		Since time.Time
+
+		// Transitions matches entries where the state changed
+		Transitions bool
+	}
+
+	// historyRing is a fixed size ring buffer of entries, oldest first
+	historyRing struct {
+		entries []HistoryEntry
+		next    int
+	}
+)
+
+func (r *historyRing) add(size int, entry HistoryEntry) {
+	if len(r.entries) < size {
+		r.entries = append(r.entries, entry)
+		return
+	}
+	r.entries[r.next] = entry
+	r.next = (r.next + 1) % len(r.entries)
+}
+
+func (r *historyRing) list() []HistoryEntry {
+	return append(append([]HistoryEntry(nil), r.entries[r.next:]...), r.entries[:r.next]...)
+}
+
+func (f HistoryFilter) match(entry HistoryEntry) bool {
+	if f.Endpoint != "" && entry.Endpoint != f.Endpoint {
+		return false
+	}
+	if f.Check != "" && entry.Check != f.Check {
+		return false
+	}
+	if !f.Since.IsZero() && entry.Time < f.Since.UnixNano() {
+		return false
+	}
+	if f.Transitions && !entry.Transition {
+		return false
+	}
+	return true
+}
+
+// record adds an entry to the history, which is bounded per endpoint and check
+func (s *Service) record(key stateKey, event Event, duration time.Duration, status *Status) {
+	if s.config.HistorySize <= 0 {
+		return
+	}
+
+	entry := HistoryEntry{
+		Endpoint:   key.endpoint,
+		Check:      key.check,
+		Old:        event.Old.String(),
+		New:        event.New.String(),
+		Transition: event.Old != event.New,
+		Message:    "OK",
+		Time:       event.Time.UnixNano(),
+		Duration:   duration.String(),
+		Status:     status,
+	}
+	if event.Error != nil {
+		entry.Message = s.redact(event.Error.Error())
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.history == nil {
+		s.history = make(map[stateKey]*historyRing)
+	}
+	ring := s.history[key]
+	if ring == nil {
+		ring = new(historyRing)
+		s.history[key] = ring
+	}
+	ring.add(s.config.HistorySize, entry)
+}
+
+// History returns the recorded evaluations of the service's endpoints and checks, matching the filter, oldest first,
+// note that up to Config.HistorySize entries are retained per endpoint, and per check
+func (s *Service) History(filter HistoryFilter) []HistoryEntry {
+	s.ensure()
+	entries := make([]HistoryEntry, 0)
+	func() {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for _, ring := range s.history {
+			for _, entry := range ring.list() {
+				if filter.match(entry) {
+					entries = append(entries, entry)
+				}
+			}
+		}
+	}()
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time < entries[j].Time
+	})
+	return entries
+}
+
+// historyHandler serves the history, supporting the `endpoint`, `check`, `since` (nanoseconds epoch or RFC3339), and
+// `transitions` query parameters
+func (s *Service) historyHandler(i *gin.Context) {
+	filter := HistoryFilter{
+		Endpoint: Endpoint(i.Query("endpoint")),
+		Check:    i.Query("check"),
+	}
+
+	if since := i.Query("since"); since != "" {
+		if nanos, err := strconv.ParseInt(since, 10, 64); err == nil {
+			filter.Since = time.Unix(0, nanos)
+		} else if filter.Since, err = time.Parse(time.RFC3339Nano, since); err != nil {
+			status := s.newStatus(fmt.Errorf("invalid since: %s", since))
+			status.Code = http.StatusBadRequest
+			i.JSON(status.Code, status)
+			return
+		}
+	}
+
+	if transitions := i.Query("transitions"); transitions != "" {
+		var err error
+		if filter.Transitions, err = strconv.ParseBool(transitions); err != nil {
+			status := s.newStatus(fmt.Errorf("invalid transitions: %s", transitions))
+			status.Code = http.StatusBadRequest
+			i.JSON(status.Code, status)
+			return
+		}
+	}
+
+	i.JSON(http.StatusOK, s.History(filter))
+}
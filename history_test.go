@@ -0,0 +1,116 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHistoryRing(t *testing.T) {
+	ring := new(historyRing)
+	for i := int64(1); i <= 5; i++ {
+		ring.add(3, HistoryEntry{Time: i})
+	}
+	entries := ring.list()
+	if len(entries) != 3 || entries[0].Time != 3 || entries[1].Time != 4 || entries[2].Time != 5 {
+		t.Error(entries)
+	}
+}
+
+func TestService_History(t *testing.T) {
+	config := NewConfig()
+	config.Logger = nil
+	config.HistorySize = 2
+	config.HistoryPath = DefaultHistoryPath
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not started, so the handlers aren't evaluated
+	service.Readiness()
+	service.Readiness()
+	service.Readiness()
+	service.Health()
+
+	// pretend we started
+	func() {
+		service.mutex.Lock()
+		defer service.mutex.Unlock()
+		service.fatal = FatalError{}
+	}()
+	since := time.Now()
+	service.Readiness()
+
+	entries := service.History(HistoryFilter{})
+	if len(entries) != 4 {
+		t.Fatal(entries)
+	}
+	for i, entry := range entries {
+		if i != 0 && entry.Time < entries[i-1].Time {
+			t.Error("expected oldest first", entries)
+		}
+	}
+
+	entries = service.History(HistoryFilter{Endpoint: EndpointReadiness, Since: since})
+	if len(entries) != 2 ||
+		entries[0].Check != HandlerCheckName || entries[0].New != "ok" || entries[0].Status != nil ||
+		entries[1].Check != "" || entries[1].Old != "failing" || entries[1].New != "ok" || !entries[1].Transition ||
+		entries[1].Status == nil || entries[1].Status.Code != 200 {
+		t.Error(entries)
+	}
+
+	for _, testCase := range []struct {
+		Query  string
+		Code   int
+		Length int
+	}{
+		{"", 200, 4},
+		{"?endpoint=health", 200, 1},
+		{"?endpoint=readiness&transitions=true", 200, 2},
+		{"?endpoint=readiness&check=handler", 200, 1},
+		{"?since=" + strconv.FormatInt(since.UnixNano(), 10), 200, 2},
+		{"?since=" + since.Format(time.RFC3339Nano), 200, 2},
+		{"?since=nope", 400, -1},
+		{"?transitions=nope", 400, -1},
+	} {
+		w := httptest.NewRecorder()
+		service.engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/history"+testCase.Query, nil))
+		if w.Code != testCase.Code {
+			t.Error(testCase, w.Code, w.Body.String())
+			continue
+		}
+		if testCase.Length < 0 {
+			continue
+		}
+		var entries []HistoryEntry
+		if err := json.Unmarshal(w.Body.Bytes(), &entries); err != nil || len(entries) != testCase.Length {
+			t.Error(testCase, err, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,292 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultListenerName is the name of the listener used if Config.Listeners is empty, which serves all routes on
+	// Config.Hostname and Config.Port
+	DefaultListenerName = "default"
+
+	// DefaultShutdownTimeout is how long in-flight requests have to complete, once the service's context is
+	// cancelled
+	DefaultShutdownTimeout = time.Second * 5
+)
+
+// Listener configures a server, see Config.Listeners
+type Listener struct {
+	// Name identifies the listener, e.g. in errors and logs, and must be unique
+	Name string
+
+	// Hostname is the hostname fragment to listen on, which defaults to an empty string (all)
+	Hostname string
+
+	// Port is the tcp port to listen on, unless UnixSocket is set
+	Port int
+
+	// UnixSocket may be set to listen on a unix domain socket at this path, instead of a tcp port, note that unix
+	// sockets are always served without TLS, any existing socket at the path will be removed
+	UnixSocket string
+
+	// Endpoints restricts the listener to the routes of these endpoints (including single checks), where an empty
+	// value serves all routes, including the version, history and metrics, which therefore require at least one
+	// listener without Endpoints
+	Endpoints []Endpoint
+}
+
+// serves returns true if the listener serves the endpoint
+func (l Listener) serves(endpoint Endpoint) bool {
+	if len(l.Endpoints) == 0 {
+		return true
+	}
+	for _, e := range l.Endpoints {
+		if e == endpoint {
+			return true
+		}
+	}
+	return false
+}
+
+func (l Listener) address() string {
+	if l.UnixSocket != "" {
+		return l.UnixSocket
+	}
+	return fmt.Sprintf("%s:%d", l.Hostname, l.Port)
+}
+
+func (l Listener) listen() (net.Listener, error) {
+	if l.UnixSocket == "" {
+		return net.Listen("tcp", l.address())
+	}
+	// remove any stale socket, but never any other kind of file
+	if info, err := os.Lstat(l.UnixSocket); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("not a socket: %s", l.UnixSocket)
+		}
+		if err := os.Remove(l.UnixSocket); err != nil {
+			return nil, err
+		}
+	}
+	return net.Listen("unix", l.UnixSocket)
+}
+
+// listeners returns the configured listeners, or the default listener
+func (c Config) listeners() []Listener {
+	if len(c.Listeners) != 0 {
+		return c.Listeners
+	}
+	return []Listener{{Name: DefaultListenerName, Hostname: c.Hostname, Port: c.Port}}
+}
+
+// servesAllRoutes returns true if any listener serves all routes, as the version, history and metrics are not
+// served otherwise
+func (c Config) servesAllRoutes() bool {
+	for _, listener := range c.listeners() {
+		if len(listener.Endpoints) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func validateListeners(listeners []Listener) error {
+	names := make(map[string]struct{}, len(listeners))
+	for i, listener := range listeners {
+		if listener.Name == "" {
+			return fmt.Errorf("empty name at index %d", i)
+		}
+		if _, ok := names[listener.Name]; ok {
+			return fmt.Errorf("duplicate name %q", listener.Name)
+		}
+		names[listener.Name] = struct{}{}
+		if listener.UnixSocket == "" && listener.Port <= 0 {
+			return fmt.Errorf("invalid port for %q: %v", listener.Name, listener.Port)
+		}
+		if listener.UnixSocket != "" && (listener.Port != 0 || listener.Hostname != "") {
+			return fmt.Errorf("unix socket with hostname or port for %q", listener.Name)
+		}
+		for _, endpoint := range listener.Endpoints {
+			if endpoint != EndpointHealth && endpoint != EndpointReadiness {
+				return fmt.Errorf("invalid endpoint for %q: %s", listener.Name, endpoint)
+			}
+		}
+	}
+	return nil
+}
+
+// newEngine creates an engine serving the routes of the given endpoints, or all routes if endpoints is empty
+func (s *Service) newEngine(endpoints []Endpoint) *gin.Engine {
+	engine := gin.New()
+
+	if s.config.Logger != nil {
+		engine.Use(s.accessLog())
+	}
+
+	engine.Use(s.config.GinHandlers...)
+
+	routes := engine.Group(s.config.PathPrefix)
+
+	if len(endpoints) == 0 {
+		if s.config.VersionPath != "" {
+			routes.GET(s.config.VersionPath, s.requireAuthorized, s.versionHandler)
+		}
+
+		if s.config.HistoryPath != "" {
+			routes.GET(s.config.HistoryPath, s.requireAuthorized, s.historyHandler)
+		}
+
+		if s.config.MetricsPath != "" {
+			routes.GET(s.config.MetricsPath, s.requireAuthorized, gin.WrapH(s.metrics.handler()))
+		}
+	}
+
+	listener := Listener{Endpoints: endpoints}
+
+	if listener.serves(EndpointHealth) {
+		for _, path := range s.config.paths(EndpointHealth) {
+			routes.GET(path, s.healthHandler)
+			routes.GET(path+"/:check", s.checkHandler(EndpointHealth))
+		}
+	}
+
+	if listener.serves(EndpointReadiness) {
+		for _, path := range s.config.paths(EndpointReadiness) {
+			routes.GET(path, s.readinessHandler)
+			routes.GET(path+"/:check", s.checkHandler(EndpointReadiness))
+		}
+	}
+
+	return engine
+}
+
+// serve runs a single listener until it fails, recording it's FatalError, where the service's FatalError is only
+// set (and it's context cancelled) once all listeners have stopped
+func (s *Service) serve(listener Listener, engine *gin.Engine) {
+	fatalError := errors.New("unknown error")
+	defer func() {
+		stopped := time.Now()
+		fatal, last := func() (FatalError, bool) {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			fatal := FatalError{
+				Error:   fmt.Errorf("listener %q (%s) stopped: %s", listener.Name, listener.address(), fatalError.Error()),
+				Time:    stopped,
+				Runtime: time.Duration(stopped.UnixNano() - s.started.UnixNano()),
+			}
+			if s.listenerFatal == nil {
+				s.listenerFatal = make(map[string]FatalError)
+			}
+			s.listenerFatal[listener.Name] = fatal
+			s.running--
+			if s.running != 0 {
+				return fatal, false
+			}
+			s.cancel()
+			if s.fatal.Error != nil {
+				// e.g. a failed worker, which was already logged
+				return fatal, false
+			}
+			s.fatal = fatal
+			return fatal, true
+		}()
+		// logged once the mutex is released, so a slow logger doesn't block probes
+		s.logListener(listener.Name, fatal)
+		if last {
+			s.logFatal(fatal)
+		}
+	}()
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		fatalError = fmt.Errorf("recovered from panic (%T): %+v", r, r)
+	}()
+	ln, err := listener.listen()
+	if err != nil {
+		fatalError = err
+		return
+	}
+	server := &http.Server{Handler: engine}
+	// shut down the server once the service's context is cancelled, e.g. due to a failed worker
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-done:
+		case <-s.ctx.Done():
+			ctx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
+			defer cancel()
+			server.Shutdown(ctx)
+		}
+	}()
+	if s.tls != nil && listener.UnixSocket == "" {
+		server.TLSConfig = s.tls
+		err = server.ServeTLS(ln, "", "")
+	} else {
+		err = server.Serve(ln)
+	}
+	if err != nil {
+		fatalError = err
+	}
+}
+
+// ListenerFatal returns the FatalError of a listener, by name (see Config.Listeners, or DefaultListenerName), if it
+// has stopped, otherwise the service's FatalError, or false if there is no such listener
+func (s *Service) ListenerFatal(name string) (FatalError, bool) {
+	s.ensure()
+	var ok bool
+	for _, listener := range s.config.listeners() {
+		if listener.Name == name {
+			ok = true
+		}
+	}
+	if !ok {
+		return FatalError{}, false
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if fatal, ok := s.listenerFatal[name]; ok {
+		return fatal, true
+	}
+	return s.fatal, true
+}
+
+// fatalError returns the service's FatalError, or that of any listener serving the endpoint, with a reason
+func (s *Service) fatalError(endpoint Endpoint) error {
+	if err := s.Fatal().Error; err != nil {
+		return withReason(err, fatalReason(err))
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, listener := range s.config.listeners() {
+		if fatal, ok := s.listenerFatal[listener.Name]; ok && listener.serves(endpoint) {
+			return withReason(fatal.Error, ReasonFatal)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig_Validate_listeners(t *testing.T) {
+	for _, testCase := range []struct {
+		Listeners   []Listener
+		MetricsPath string
+		Error       string
+	}{
+		{nil, "", ""},
+		{[]Listener{{Name: "health", Port: 1, Endpoints: []Endpoint{EndpointHealth}}, {Name: "readiness", UnixSocket: "/tmp/readiness.sock"}}, "", ""},
+		{[]Listener{{Port: 1}}, "", "empty name"},
+		{[]Listener{{Name: "a", Port: 1}, {Name: "a", Port: 2}}, "", "duplicate name"},
+		{[]Listener{{Name: "a"}}, "", "invalid port"},
+		{[]Listener{{Name: "a", UnixSocket: "/tmp/a.sock", Port: 1}}, "", "unix socket with hostname or port"},
+		{[]Listener{{Name: "a", Port: 1, Endpoints: []Endpoint{"metrics"}}}, "", "invalid endpoint"},
+		{[]Listener{{Name: "health", Port: 1, Endpoints: []Endpoint{EndpointHealth}}, {Name: "readiness", Port: 2, Endpoints: []Endpoint{EndpointReadiness}}}, "/metrics", "requires a listener serving all routes"},
+		{[]Listener{{Name: "health", Port: 1, Endpoints: []Endpoint{EndpointHealth}}, {Name: "admin", Port: 2}}, "/metrics", ""},
+		{nil, "/metrics", ""},
+	} {
+		config := NewConfig()
+		config.HealthHandler = func() error { return nil }
+		config.ReadinessHandler = func() error { return nil }
+		config.Listeners = testCase.Listeners
+		config.MetricsPath = testCase.MetricsPath
+		err := config.Validate()
+		if testCase.Error == "" {
+			if err != nil {
+				t.Error(err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), testCase.Error) {
+			t.Error(testCase.Error, err)
+		}
+	}
+}
+
+func TestService_listeners(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kubestatus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "readiness.sock")
+
+	// a stale socket is removed
+	if ln, err := net.Listen("unix", socket); err != nil {
+		t.Fatal(err)
+	} else {
+		ln.(*net.UnixListener).SetUnlinkOnClose(false)
+		ln.Close()
+	}
+
+	config := NewConfig()
+	config.Logger = nil
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.Listeners = []Listener{
+		{Name: "admin", Hostname: "127.0.0.1", Port: 9071, Endpoints: []Endpoint{EndpointHealth}},
+		{Name: "pod", UnixSocket: socket, Endpoints: []Endpoint{EndpointReadiness}},
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fatal, ok := service.ListenerFatal("admin"); !ok || fatal.Error == nil {
+		t.Error(fatal, ok)
+	}
+	if _, ok := service.ListenerFatal("unknown"); ok {
+		t.Error("expected unknown listener")
+	}
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if fatal, ok := service.ListenerFatal("admin"); !ok || fatal.Error != nil {
+		t.Error(fatal, ok)
+	}
+
+	get := func(client *http.Client, URL string) int {
+		resp, err := client.Get(URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+
+	for _, testCase := range []struct {
+		Client *http.Client
+		URL    string
+		Code   int
+	}{
+		{http.DefaultClient, "http://127.0.0.1:9071/healthz", 200},
+		{http.DefaultClient, "http://127.0.0.1:9071/healthz/handler", 200},
+		{http.DefaultClient, "http://127.0.0.1:9071/readiness", 404},
+		{http.DefaultClient, "http://127.0.0.1:9071/version", 404},
+		{unixClient, "http://unix/readiness", 200},
+		{unixClient, "http://unix/readiness/handler", 200},
+		{unixClient, "http://unix/healthz", 404},
+	} {
+		if code := get(testCase.Client, testCase.URL); code != testCase.Code {
+			t.Error(testCase.URL, code)
+		}
+	}
+
+	// a listener failing (the admin port is in use) only fails the endpoints it serves
+	output := new(syncBuffer)
+	other := NewConfig()
+	other.Logger = slog.New(slog.NewJSONHandler(output, nil))
+	other.HealthHandler = config.HealthHandler
+	other.ReadinessHandler = config.ReadinessHandler
+	other.Listeners = []Listener{
+		{Name: "admin", Hostname: "127.0.0.1", Port: 9071, Endpoints: []Endpoint{EndpointHealth}},
+		{Name: "pod", Hostname: "127.0.0.1", Port: 9072, Endpoints: []Endpoint{EndpointReadiness}},
+	}
+	otherService, err := NewService(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := otherService.Start(); err == nil || !strings.Contains(err.Error(), `listener "admin"`) {
+		t.Fatal(err)
+	}
+	if fatal := otherService.Fatal(); fatal.Error != nil {
+		t.Error(fatal)
+	}
+	if fatal, _ := otherService.ListenerFatal("admin"); fatal.Error == nil {
+		t.Error(fatal)
+	}
+	if status := otherService.Health(); status.Success || status.Reason != ReasonFatal {
+		t.Error(status)
+	}
+	if status := otherService.Readiness(); !status.Success {
+		t.Error(status)
+	}
+	if code := get(http.DefaultClient, "http://127.0.0.1:9072/readiness"); code != 200 {
+		t.Error(code)
+	}
+
+	// only the failed listener is logged, as the service is still serving
+	var messages []string
+	for _, record := range output.records(t) {
+		if record["level"] == "ERROR" {
+			messages = append(messages, fmt.Sprint(record["msg"], " ", record["listener"]))
+		}
+	}
+	if actual := strings.Join(messages, ","); actual != "kubestatus listener stopped admin" {
+		t.Error(actual)
+	}
+}
+
+func TestConfig_URL_listeners(t *testing.T) {
+	config := NewConfig()
+	config.Listeners = []Listener{
+		{Name: "pod", UnixSocket: "/tmp/readiness.sock"},
+		{Name: "admin", Hostname: "127.0.0.1", Port: 9000},
+	}
+	if URL := config.URL(); URL != "http://127.0.0.1:9000" {
+		t.Error(URL)
+	}
+	config.Listeners = config.Listeners[:1]
+	if URL := config.URL(); URL != "" {
+		t.Error(URL)
+	}
+}
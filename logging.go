@@ -0,0 +1,114 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func (s *Service) logger() *slog.Logger {
+	if s.config.Logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return s.config.Logger.With("uuid", uuid.UUID(s.uuid).String())
+}
+
+// logTransition logs a change in state of an endpoint or check
+func (s *Service) logTransition(ctx context.Context, event Event, duration time.Duration) {
+	attrs := []slog.Attr{
+		slog.String("endpoint", string(event.Endpoint)),
+		slog.String("from", event.Old.String()),
+		slog.String("to", event.New.String()),
+		slog.Duration("duration", duration),
+	}
+	if event.Check != "" {
+		attrs = append(attrs, slog.String("check", event.Check))
+	}
+	level := slog.LevelInfo
+	if event.Error != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", event.Error.Error()))
+	}
+
+	message := "kubestatus endpoint state changed"
+	if event.Check != "" {
+		message = "kubestatus check state changed"
+	}
+
+	s.logger().LogAttrs(ctx, level, message, attrs...)
+}
+
+func (s *Service) logFatal(fatal FatalError) {
+	s.logger().LogAttrs(
+		context.Background(),
+		slog.LevelError,
+		"kubestatus service stopped",
+		slog.String("error", fatal.Error.Error()),
+		slog.Duration("runtime", fatal.Runtime),
+	)
+}
+
+func (s *Service) logListener(name string, fatal FatalError) {
+	s.logger().LogAttrs(
+		context.Background(),
+		slog.LevelError,
+		"kubestatus listener stopped",
+		slog.String("listener", name),
+		slog.String("error", fatal.Error.Error()),
+		slog.Duration("runtime", fatal.Runtime),
+	)
+}
+
+func (s *Service) logWorker(name string, err error) {
+	s.logger().LogAttrs(
+		context.Background(),
+		slog.LevelError,
+		"kubestatus worker failed",
+		slog.String("worker", name),
+		slog.String("error", err.Error()),
+	)
+}
+
+// accessLog logs requests, which by default excludes successful (2xx) responses, to avoid logging every probe
+func (s *Service) accessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		status := c.Writer.Status()
+		if statusOK(status) && !s.config.LogSuccess {
+			return
+		}
+		level := slog.LevelInfo
+		if !statusOK(status) {
+			level = slog.LevelWarn
+		}
+		s.logger().LogAttrs(
+			c.Request.Context(),
+			level,
+			"kubestatus request",
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
@@ -0,0 +1,159 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"github.com/gin-gonic/gin"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *syncBuffer) records(t *testing.T) []map[string]interface{} {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	var records []map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(b.buffer.Bytes()))
+	for decoder.More() {
+		record := make(map[string]interface{})
+		if err := decoder.Decode(&record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	b.buffer.Reset()
+	return records
+}
+
+func TestService_logging(t *testing.T) {
+	var ready int32
+	output := new(syncBuffer)
+
+	config := NewConfig()
+	config.Port = 9067
+	config.GinHandlers = nil
+	gin.SetMode(gin.ReleaseMode)
+	config.Logger = slog.New(slog.NewJSONHandler(output, nil))
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.ReadinessChecks = []Check{
+		{
+			Name: "database",
+			Handler: func() error {
+				if atomic.LoadInt32(&ready) == 0 {
+					return errors.New("not connected")
+				}
+				return nil
+			},
+		},
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	client := Client{Addresses: []string{"http://localhost:9067"}}
+
+	if _, err := client.Health(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Readiness(); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	type record struct {
+		Level, Msg, Endpoint, Check, From, To, Path string
+	}
+	check := func(expected ...record) {
+		t.Helper()
+		var actual []record
+		for _, r := range output.records(t) {
+			if r["uuid"] == nil {
+				t.Error("expected a uuid", r)
+			}
+			str := func(key string) string {
+				s, _ := r[key].(string)
+				return s
+			}
+			actual = append(actual, record{
+				Level:    str("level"),
+				Msg:      str("msg"),
+				Endpoint: str("endpoint"),
+				Check:    str("check"),
+				From:     str("from"),
+				To:       str("to"),
+				Path:     str("path"),
+			})
+		}
+		if len(actual) != len(expected) {
+			t.Fatalf("expected %+v\nactual %+v", expected, actual)
+		}
+		for i := range expected {
+			if actual[i] != expected[i] {
+				t.Errorf("expected %+v\nactual %+v", expected[i], actual[i])
+			}
+		}
+	}
+
+	check(
+		record{"INFO", "kubestatus check state changed", "health", "handler", "unknown", "ok", ""},
+		record{"INFO", "kubestatus endpoint state changed", "health", "", "unknown", "ok", ""},
+		record{"INFO", "kubestatus check state changed", "readiness", "handler", "unknown", "ok", ""},
+		record{"WARN", "kubestatus check state changed", "readiness", "database", "unknown", "failing", ""},
+		record{"WARN", "kubestatus endpoint state changed", "readiness", "", "unknown", "failing", ""},
+		record{"WARN", "kubestatus request", "", "", "", "", "/readiness"},
+	)
+
+	// no changes, successful requests are not logged
+	client.Health()
+	client.Readiness()
+	check(
+		record{"WARN", "kubestatus request", "", "", "", "", "/readiness"},
+	)
+
+	atomic.StoreInt32(&ready, 1)
+	if _, err := client.Readiness(); err != nil {
+		t.Fatal(err)
+	}
+	check(
+		record{"INFO", "kubestatus check state changed", "readiness", "database", "failing", "ok", ""},
+		record{"INFO", "kubestatus endpoint state changed", "readiness", "", "failing", "ok", ""},
+	)
+}
@@ -0,0 +1,195 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"net/http"
+	"time"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics implements prometheus.Collector for a service, all metrics are labeled with the service's UUID
+type metrics struct {
+	service *Service
+
+	up                 *prometheus.GaugeVec
+	checkUp            *prometheus.GaugeVec
+	checkFailures      *prometheus.CounterVec
+	checkDuration      *prometheus.HistogramVec
+	dependencyFailures *prometheus.CounterVec
+	dependencyDuration *prometheus.HistogramVec
+	uptime             *prometheus.Desc
+	fatal              *prometheus.Desc
+}
+
+func newMetrics(service *Service) *metrics {
+	labels := prometheus.Labels{"uuid": uuid.UUID(service.uuid).String()}
+	return &metrics{
+		service: service,
+		up: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:        "kubestatus_up",
+				Help:        "Whether the endpoint was successful (1) or not (0), as of it's last evaluation.",
+				ConstLabels: labels,
+			},
+			[]string{"endpoint"},
+		),
+		checkUp: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name:        "kubestatus_check_up",
+				Help:        "Whether the check was successful (1) or not (0), as of it's last evaluation.",
+				ConstLabels: labels,
+			},
+			[]string{"endpoint", "check"},
+		),
+		checkFailures: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "kubestatus_check_failures_total",
+				Help:        "Total number of failed evaluations of the check.",
+				ConstLabels: labels,
+			},
+			[]string{"endpoint", "check"},
+		),
+		checkDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        "kubestatus_check_duration_seconds",
+				Help:        "Latency of evaluating the check.",
+				ConstLabels: labels,
+				Buckets:     prometheus.DefBuckets,
+			},
+			[]string{"endpoint", "check"},
+		),
+		dependencyFailures: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name:        "kubestatus_dependency_failures_total",
+				Help:        "Total number of failed readiness checks of the dependency.",
+				ConstLabels: labels,
+			},
+			[]string{"dependency"},
+		),
+		dependencyDuration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:        "kubestatus_dependency_duration_seconds",
+				Help:        "Latency of checking the readiness of the dependency.",
+				ConstLabels: labels,
+				Buckets:     prometheus.DefBuckets,
+			},
+			[]string{"dependency"},
+		),
+		uptime: prometheus.NewDesc(
+			"kubestatus_uptime_seconds",
+			"Seconds since the service was started, or 0 if it has not been started.",
+			nil,
+			labels,
+		),
+		fatal: prometheus.NewDesc(
+			"kubestatus_fatal",
+			"Whether the service has a fatal error (1) or not (0).",
+			nil,
+			labels,
+		),
+	}
+}
+
+func (m *metrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{
+		m.up,
+		m.checkUp,
+		m.checkFailures,
+		m.checkDuration,
+		m.dependencyFailures,
+		m.dependencyDuration,
+	}
+}
+
+// Describe implements prometheus.Collector
+func (m *metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range m.collectors() {
+		collector.Describe(ch)
+	}
+	ch <- m.uptime
+	ch <- m.fatal
+}
+
+// Collect implements prometheus.Collector
+func (m *metrics) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range m.collectors() {
+		collector.Collect(ch)
+	}
+
+	var (
+		fatal   float64
+		started time.Time
+	)
+	func() {
+		m.service.mutex.Lock()
+		defer m.service.mutex.Unlock()
+		if m.service.fatal.Error != nil {
+			fatal = 1
+		}
+		started = m.service.started
+	}()
+
+	var uptime float64
+	if !started.IsZero() {
+		uptime = time.Since(started).Seconds()
+	}
+
+	ch <- prometheus.MustNewConstMetric(m.uptime, prometheus.GaugeValue, uptime)
+	ch <- prometheus.MustNewConstMetric(m.fatal, prometheus.GaugeValue, fatal)
+}
+
+func (m *metrics) handler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(m)
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+func (m *metrics) observe(endpoint Endpoint, status Status) {
+	m.up.WithLabelValues(string(endpoint)).Set(boolGauge(status.Success))
+}
+
+func (m *metrics) check(endpoint Endpoint, name string, duration time.Duration, err error) {
+	m.checkDuration.WithLabelValues(string(endpoint), name).Observe(duration.Seconds())
+	m.checkUp.WithLabelValues(string(endpoint), name).Set(boolGauge(err == nil))
+	if err != nil {
+		m.checkFailures.WithLabelValues(string(endpoint), name).Inc()
+	}
+}
+
+func (m *metrics) dependency(address string, duration time.Duration, err error) {
+	m.dependencyDuration.WithLabelValues(address).Observe(duration.Seconds())
+	if err != nil {
+		m.dependencyFailures.WithLabelValues(address).Inc()
+	}
+}
+
+func boolGauge(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Collector returns a prometheus collector exporting metrics for this service, which may be registered with an
+// existing registry, see also Config.MetricsPath
+func (s *Service) Collector() prometheus.Collector {
+	s.ensure()
+	return s.metrics
+}
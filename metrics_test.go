@@ -0,0 +1,113 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestService_metrics(t *testing.T) {
+	config := NewConfig()
+	config.Port = 9064
+	config.GinHandlers = nil
+	gin.SetMode(gin.ReleaseMode)
+	config.MetricsPath = "/metrics"
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.ReadinessChecks = []Check{
+		{
+			Name: "database",
+			Handler: func() error {
+				return errors.New("not connected")
+			},
+		},
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(service.Collector()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP kubestatus_fatal Whether the service has a fatal error (1) or not (0).
+# TYPE kubestatus_fatal gauge
+kubestatus_fatal{uuid="`+uuid.UUID(service.UUID()).String()+`"} 1
+`), "kubestatus_fatal"); err != nil {
+		t.Error(err)
+	}
+
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	service.Health()
+	service.Readiness()
+	service.Readiness()
+
+	labels := `uuid="` + uuid.UUID(service.UUID()).String() + `"`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP kubestatus_check_failures_total Total number of failed evaluations of the check.
+# TYPE kubestatus_check_failures_total counter
+kubestatus_check_failures_total{check="database",endpoint="readiness",`+labels+`} 2
+# HELP kubestatus_check_up Whether the check was successful (1) or not (0), as of it's last evaluation.
+# TYPE kubestatus_check_up gauge
+kubestatus_check_up{check="database",endpoint="readiness",`+labels+`} 0
+kubestatus_check_up{check="handler",endpoint="health",`+labels+`} 1
+kubestatus_check_up{check="handler",endpoint="readiness",`+labels+`} 1
+# HELP kubestatus_fatal Whether the service has a fatal error (1) or not (0).
+# TYPE kubestatus_fatal gauge
+kubestatus_fatal{`+labels+`} 0
+# HELP kubestatus_up Whether the endpoint was successful (1) or not (0), as of it's last evaluation.
+# TYPE kubestatus_up gauge
+kubestatus_up{endpoint="health",`+labels+`} 1
+kubestatus_up{endpoint="readiness",`+labels+`} 0
+`), "kubestatus_check_failures_total", "kubestatus_check_up", "kubestatus_fatal", "kubestatus_up"); err != nil {
+		t.Error(err)
+	}
+	if count := testutil.CollectAndCount(service.Collector(), "kubestatus_check_duration_seconds"); count != 3 {
+		t.Error(count)
+	}
+
+	resp, err := http.Get("http://localhost:9064/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(b), "kubestatus_uptime_seconds{"+labels+"}") {
+		t.Error(resp.StatusCode, string(b))
+	}
+}
@@ -0,0 +1,129 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// PodNameEnv is the environment variable LoadPodInfo reads the pod name from, e.g. via fieldRef metadata.name
+	PodNameEnv = "POD_NAME"
+
+	// PodNamespaceEnv is the environment variable LoadPodInfo reads the pod namespace from, e.g. via fieldRef
+	// metadata.namespace
+	PodNamespaceEnv = "POD_NAMESPACE"
+
+	// NodeNameEnv is the environment variable LoadPodInfo reads the node name from, e.g. via fieldRef spec.nodeName
+	NodeNameEnv = "NODE_NAME"
+)
+
+// PodInfo identifies the Kubernetes pod running the service, see Config.Pod
+type PodInfo struct {
+	// Name is the name of the pod
+	Name string `json:"name,omitempty"`
+
+	// Namespace is the namespace of the pod
+	Namespace string `json:"namespace,omitempty"`
+
+	// Node is the name of the node the pod is scheduled on
+	Node string `json:"node,omitempty"`
+
+	// Labels are the pod's labels, in the form of an (equality based) label selector, e.g. `app=foo,tier=web`
+	Labels string `json:"labels,omitempty"`
+}
+
+// String returns namespace/name, or just the name, if there is no namespace
+func (p PodInfo) String() string {
+	if p.Namespace == "" {
+		return p.Name
+	}
+	return p.Namespace + "/" + p.Name
+}
+
+// LoadPodInfo loads pod info using the Kubernetes downward API, from the environment variables PodNameEnv,
+// PodNamespaceEnv and NodeNameEnv, and, if dir is non-empty, from the downward API volume files `name`,
+// `namespace`, `node` and `labels` within dir, where any files that exist take precedence
+func LoadPodInfo(dir string) (PodInfo, error) {
+	pod := PodInfo{
+		Name:      os.Getenv(PodNameEnv),
+		Namespace: os.Getenv(PodNamespaceEnv),
+		Node:      os.Getenv(NodeNameEnv),
+	}
+
+	if dir == "" {
+		return pod, nil
+	}
+
+	for _, field := range []struct {
+		File  string
+		Value *string
+	}{
+		{"name", &pod.Name},
+		{"namespace", &pod.Namespace},
+		{"node", &pod.Node},
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, field.File))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return PodInfo{}, fmt.Errorf("kubestatus.LoadPodInfo failed to read %s: %s", field.File, err.Error())
+		}
+		*field.Value = strings.TrimSpace(string(b))
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "labels"))
+	if os.IsNotExist(err) {
+		return pod, nil
+	}
+	if err != nil {
+		return PodInfo{}, fmt.Errorf("kubestatus.LoadPodInfo failed to read labels: %s", err.Error())
+	}
+	if pod.Labels, err = parseDownwardLabels(string(b)); err != nil {
+		return PodInfo{}, fmt.Errorf("kubestatus.LoadPodInfo failed to parse labels: %s", err.Error())
+	}
+
+	return pod, nil
+}
+
+// parseDownwardLabels parses the downward API format, `key="value"` per line, returning a sorted label selector
+func parseDownwardLabels(s string) (string, error) {
+	var labels []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		index := strings.Index(line, "=")
+		if index <= 0 {
+			return "", fmt.Errorf("invalid line: %s", line)
+		}
+		value, err := strconv.Unquote(line[index+1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid value for %s: %s", line[:index], err.Error())
+		}
+		labels = append(labels, line[:index]+"="+value)
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, ","), nil
+}
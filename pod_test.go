@@ -0,0 +1,94 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPodInfo(t *testing.T) {
+	t.Setenv(PodNameEnv, "env-pod")
+	t.Setenv(PodNamespaceEnv, "env-namespace")
+	t.Setenv(NodeNameEnv, "env-node")
+
+	if pod, err := LoadPodInfo(""); err != nil || pod != (PodInfo{Name: "env-pod", Namespace: "env-namespace", Node: "env-node"}) {
+		t.Error(pod, err)
+	}
+
+	dir, err := os.MkdirTemp("", "kubestatus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "name"), []byte("file-pod\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "labels"), []byte("tier=\"web\"\napp=\"foo \\\"bar\\\"\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	pod, err := LoadPodInfo(dir)
+	if err != nil || pod != (PodInfo{Name: "file-pod", Namespace: "env-namespace", Node: "env-node", Labels: `app=foo "bar",tier=web`}) {
+		t.Error(pod, err)
+	}
+	if pod.String() != "env-namespace/file-pod" {
+		t.Error(pod.String())
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "labels"), []byte("invalid\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPodInfo(dir); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestService_pod(t *testing.T) {
+	config := NewConfig()
+	config.Logger = nil
+	config.Pod = PodInfo{Name: "foo-abc", Namespace: "default"}
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not started, so not ready
+	if status := service.Readiness(); status.Pod != config.Pod {
+		t.Error(status)
+	}
+
+	server := httptest.NewServer(service.engine)
+	defer server.Close()
+
+	statuses, err := Client{Addresses: []string{server.URL}}.Readiness()
+	if err == nil || err.Error() != "503 Service Unavailable: pod default/foo-abc: kubestatus.Service has not been started yet" {
+		t.Error(err)
+	}
+	if statuses[0] == nil || statuses[0].Pod != config.Pod {
+		t.Error(statuses[0])
+	}
+}
@@ -0,0 +1,88 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"context"
+	"errors"
+)
+
+const (
+	// ReasonNotStarted indicates the service has not been started
+	ReasonNotStarted Reason = "NotStarted"
+	// ReasonFatal indicates the service has a fatal error, e.g. the server stopped, see FatalError
+	ReasonFatal Reason = "Fatal"
+	// ReasonWorkerFailed indicates a worker failed, see Service.Go
+	ReasonWorkerFailed Reason = "WorkerFailed"
+	// ReasonCheckFailed indicates a handler or named check failed
+	ReasonCheckFailed Reason = "CheckFailed"
+	// ReasonDependencyFailed indicates a dependency is not ready, see Config.Dependencies
+	ReasonDependencyFailed Reason = "DependencyFailed"
+	// ReasonCycleDetected indicates a cyclic dependency was detected, via the UUIDs
+	ReasonCycleDetected Reason = "CycleDetected"
+	// ReasonTimeout indicates a check or dependency failed with a timeout (context.DeadlineExceeded)
+	ReasonTimeout Reason = "Timeout"
+	// ReasonDraining indicates the service is draining, and is therefore not ready, it's never set by the service,
+	// but may be returned by a readiness handler, e.g. `&StatusError{Reason: ReasonDraining, Err: err}`
+	ReasonDraining Reason = "Draining"
+)
+
+// errNotStarted is the initial fatal error, until the service is started
+var errNotStarted = errors.New("kubestatus.Service has not been started yet")
+
+// withReason returns err wrapped in a *StatusError with the reason, or ReasonTimeout if it is a timeout, unless it
+// is nil, or already has a reason, any code or retry after are preserved
+func withReason(err error, reason Reason) error {
+	if err == nil {
+		return nil
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.Reason != "" {
+		return err
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		reason = ReasonTimeout
+	}
+	result := &StatusError{Reason: reason, Err: err}
+	if statusErr != nil {
+		result.Code = statusErr.Code
+		result.RetryAfter = statusErr.RetryAfter
+	}
+	return result
+}
+
+// timeoutError is err, that is also a context.DeadlineExceeded, for timeouts that wouldn't otherwise be reported as
+// such, e.g. a killed command, see ReasonTimeout
+type timeoutError struct {
+	err error
+}
+
+func (e timeoutError) Error() string {
+	return e.err.Error()
+}
+
+func (e timeoutError) Unwrap() []error {
+	return []error{e.err, context.DeadlineExceeded}
+}
+
+// fatalReason returns the reason for a (non-nil) fatal error
+func fatalReason(err error) Reason {
+	if errors.Is(err, errNotStarted) {
+		return ReasonNotStarted
+	}
+	return ReasonFatal
+}
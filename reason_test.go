@@ -0,0 +1,174 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"github.com/google/uuid"
+)
+
+func TestWithReason(t *testing.T) {
+	if err := withReason(nil, ReasonCheckFailed); err != nil {
+		t.Fatal(err)
+	}
+	for _, testCase := range []struct {
+		Err    error
+		Reason Reason
+		Code   int
+	}{
+		{errors.New("failed"), ReasonCheckFailed, 503},
+		{fmt.Errorf("wrapped: %w", context.DeadlineExceeded), ReasonTimeout, 503},
+		{&StatusError{Code: http.StatusTooManyRequests}, ReasonCheckFailed, 429},
+		{&StatusError{Code: http.StatusTooManyRequests, Reason: "Overloaded"}, "Overloaded", 429},
+	} {
+		err := withReason(testCase.Err, ReasonCheckFailed)
+		if err.Error() != testCase.Err.Error() {
+			t.Error(err)
+		}
+		if status := NewStatus([16]byte{}, time.Time{}, err); status.Reason != testCase.Reason || status.Code != testCase.Code {
+			t.Error(testCase.Err, status)
+		}
+	}
+}
+
+func TestService_reason(t *testing.T) {
+	var (
+		healthErr    error
+		readinessErr error
+	)
+	dependency := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer dependency.Close()
+
+	config := NewConfig()
+	config.Logger = nil
+	config.HealthHandler = func() error {
+		return healthErr
+	}
+	config.ReadinessHandler = func() error {
+		return readinessErr
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status := service.Health(); status.Reason != ReasonNotStarted {
+		t.Error(status)
+	}
+	if status, _ := service.Check(EndpointReadiness, HandlerCheckName); status.Reason != ReasonNotStarted {
+		t.Error(status)
+	}
+
+	// pretend we started
+	func() {
+		service.mutex.Lock()
+		defer service.mutex.Unlock()
+		service.fatal = FatalError{}
+	}()
+
+	if status := service.Readiness(); !status.Success || status.Reason != "" {
+		t.Error(status)
+	}
+
+	readinessErr = errors.New("not ready")
+	if status := service.Readiness(); status.Reason != ReasonCheckFailed {
+		t.Error(status)
+	}
+	if status, _ := service.Check(EndpointReadiness, HandlerCheckName); status.Reason != ReasonCheckFailed {
+		t.Error(status)
+	}
+	readinessErr = &StatusError{Reason: ReasonDraining, Err: errors.New("shutting down")}
+	if status := service.Readiness(); status.Reason != ReasonDraining || status.Code != http.StatusServiceUnavailable {
+		t.Error(status)
+	}
+	readinessErr = fmt.Errorf("query: %w", context.DeadlineExceeded)
+	if status := service.Readiness(); status.Reason != ReasonTimeout {
+		t.Error(status)
+	}
+	readinessErr = nil
+
+	previous := uuid.UUID(service.UUID()).String()
+	if status := service.Readiness(previous, "other"); status.Reason != ReasonCycleDetected || status.Code != http.StatusLoopDetected {
+		t.Error(status)
+	}
+
+	service.config.Dependencies = []string{dependency.URL}
+	if status := service.Readiness(); status.Reason != ReasonDependencyFailed {
+		t.Error(status)
+	}
+	service.config.Dependencies = nil
+
+	service.Go("worker", func(ctx context.Context) error {
+		return errors.New("failed")
+	})
+	for service.Health().Success {
+		time.Sleep(time.Millisecond)
+	}
+	if status := service.Health(); status.Reason != ReasonWorkerFailed {
+		t.Error(status)
+	}
+	if status, _ := service.Check(EndpointHealth, HandlerCheckName); status.Success || status.Reason != ReasonWorkerFailed {
+		t.Error(status)
+	}
+
+	func() {
+		service.mutex.Lock()
+		defer service.mutex.Unlock()
+		service.fatal = FatalError{Error: errors.New("server stopped")}
+	}()
+	if status := service.Health(); status.Reason != ReasonFatal {
+		t.Error(status)
+	}
+}
+
+func TestClient_Get_clientError(t *testing.T) {
+	config := NewConfig()
+	config.Logger = nil
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(service.engine)
+	defer server.Close()
+
+	_, err = Client{Addresses: []string{server.URL}}.Readiness()
+	var clientErr *ClientError
+	if !errors.As(err, &clientErr) {
+		t.Fatal(err)
+	}
+	if clientErr.Address != server.URL ||
+		clientErr.Code != http.StatusServiceUnavailable ||
+		clientErr.Reason != ReasonNotStarted ||
+		clientErr.Status == nil ||
+		clientErr.Error() != "503 Service Unavailable: kubestatus.Service has not been started yet" {
+		t.Error(clientErr)
+	}
+}
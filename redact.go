@@ -0,0 +1,90 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"regexp"
+)
+
+// GenericMessage replaces all error messages exposed by the service if RedactionPolicy.Generic is set
+const GenericMessage = "unavailable, see the service logs for details"
+
+var (
+	urlUserinfoPattern = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.\-]*://)[^/?#@\s]+@`)
+	keyValuePattern    = regexp.MustCompile(`(?i)\b((?:[a-z0-9_\-]*)(?:password|passwd|pwd|secret|token|api[_\-]?key|access[_\-]?key|private[_\-]?key|credentials?))(\s*[=:]\s*)("[^"]*"|'[^']*'|[^\s&;,]+)`)
+)
+
+type (
+	// Sanitizer transforms an error message before it's exposed, e.g. to remove secrets
+	Sanitizer func(message string) string
+
+	// RedactionPolicy configures how error messages are sanitized before they are exposed by the service, in the
+	// Status, check results, history, webhooks and spans, logs always include the full error, see Config.Redaction
+	RedactionPolicy struct {
+		// Sanitizers are applied in order, after the defaults (RedactURLUserinfo and RedactSecrets)
+		Sanitizers []Sanitizer
+
+		// DisableDefaults, if true, will skip the default sanitizers
+		DisableDefaults bool
+
+		// MaxMessageLength may be set to truncate messages, after sanitizing
+		MaxMessageLength int
+
+		// Generic, if true, will replace all error messages with GenericMessage, the full errors are still logged
+		// (see Config.Logger)
+		Generic bool
+	}
+)
+
+// RedactURLUserinfo replaces the userinfo (e.g. `user:password@`) of any URLs in the message, with `***@`
+func RedactURLUserinfo(message string) string {
+	return urlUserinfoPattern.ReplaceAllString(message, "${1}***@")
+}
+
+// RedactSecrets replaces the values of any key=value (or key: value) pairs in the message, where the key looks like
+// a secret, e.g. `password`, `db_password`, `token`, `api_key`, with `***`
+func RedactSecrets(message string) string {
+	return keyValuePattern.ReplaceAllString(message, "${1}${2}***")
+}
+
+// Apply returns message, after applying the policy
+func (p RedactionPolicy) Apply(message string) string {
+	if p.Generic {
+		return GenericMessage
+	}
+	if !p.DisableDefaults {
+		message = RedactSecrets(RedactURLUserinfo(message))
+	}
+	for _, sanitizer := range p.Sanitizers {
+		if sanitizer != nil {
+			message = sanitizer(message)
+		}
+	}
+	if p.MaxMessageLength > 0 && len(message) > p.MaxMessageLength {
+		const suffix = "..."
+		if p.MaxMessageLength <= len(suffix) {
+			return truncate(message, p.MaxMessageLength)
+		}
+		message = truncate(message, p.MaxMessageLength-len(suffix)) + suffix
+	}
+	return message
+}
+
+// redact applies the configured redaction policy to an error message
+func (s *Service) redact(message string) string {
+	return s.config.Redaction.Apply(message)
+}
@@ -0,0 +1,84 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// DefaultRetryableCodes are the HTTP status codes retried if RetryPolicy.RetryableCodes is nil
+var DefaultRetryableCodes = []int{
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy configures retrying requests to each address, see Client.Retry, connection errors (including attempt
+// timeouts) are always retried, up to MaxAttempts
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts per address, a value less than 2 will disable retries
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry, which doubles for each subsequent retry
+	Backoff time.Duration
+
+	// MaxBackoff may be set to cap the delay between retries
+	MaxBackoff time.Duration
+
+	// Jitter is the fraction (between 0 and 1) of each delay to randomise, e.g. 0.2 will randomise each delay by
+	// up to +/- 20%
+	Jitter float64
+
+	// RetryableCodes are the HTTP status codes that will be retried, defaults to DefaultRetryableCodes if nil
+	RetryableCodes []int
+
+	// AttemptTimeout may be set to limit the duration of each attempt
+	AttemptTimeout time.Duration
+}
+
+func (r RetryPolicy) retryable(code int) bool {
+	codes := r.RetryableCodes
+	if codes == nil {
+		codes = DefaultRetryableCodes
+	}
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay after the given (1 indexed) attempt
+func (r RetryPolicy) backoff(attempt int) time.Duration {
+	delay := r.Backoff
+	for i := 1; i < attempt && (r.MaxBackoff <= 0 || delay < r.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if r.MaxBackoff > 0 && delay > r.MaxBackoff {
+		delay = r.MaxBackoff
+	}
+	if r.Jitter > 0 {
+		delay += time.Duration(float64(delay) * r.Jitter * (rand.Float64()*2 - 1))
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
@@ -0,0 +1,109 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	for _, testCase := range []struct {
+		Policy  RetryPolicy
+		Attempt int
+		Delay   time.Duration
+	}{
+		{RetryPolicy{}, 1, 0},
+		{RetryPolicy{Backoff: time.Second}, 1, time.Second},
+		{RetryPolicy{Backoff: time.Second}, 3, time.Second * 4},
+		{RetryPolicy{Backoff: time.Second, MaxBackoff: time.Second * 3}, 3, time.Second * 3},
+		{RetryPolicy{Backoff: time.Second, MaxBackoff: time.Second * 3}, 100, time.Second * 3},
+	} {
+		if delay := testCase.Policy.backoff(testCase.Attempt); delay != testCase.Delay {
+			t.Error(testCase, delay)
+		}
+	}
+
+	policy := RetryPolicy{Backoff: time.Second, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if delay := policy.backoff(1); delay < time.Millisecond*500 || delay > time.Millisecond*1500 {
+			t.Fatal(delay)
+		}
+	}
+}
+
+func TestClient_Get_retry(t *testing.T) {
+	for _, testCase := range []struct {
+		Policy   RetryPolicy
+		Failures int32
+		Code     int
+		Sleep    time.Duration
+		Requests int32
+		Error    bool
+	}{
+		{RetryPolicy{}, 1, http.StatusServiceUnavailable, 0, 1, true},
+		{RetryPolicy{MaxAttempts: 3}, 2, http.StatusServiceUnavailable, 0, 3, false},
+		{RetryPolicy{MaxAttempts: 3}, 3, http.StatusBadGateway, 0, 3, true},
+		{RetryPolicy{MaxAttempts: 3}, 1, http.StatusInternalServerError, 0, 1, true},
+		{RetryPolicy{MaxAttempts: 3, RetryableCodes: []int{http.StatusInternalServerError}}, 1, http.StatusInternalServerError, 0, 2, false},
+		{RetryPolicy{MaxAttempts: 2, AttemptTimeout: time.Millisecond * 50}, 0, 0, time.Millisecond * 200, 2, false},
+		{RetryPolicy{AttemptTimeout: time.Millisecond * 50}, 0, 0, time.Millisecond * 200, 1, true},
+	} {
+		func() {
+			var requests int32
+			// each case has it's own server, so no handler outlives the case
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := atomic.AddInt32(&requests, 1)
+				if n == 1 && testCase.Sleep > 0 {
+					select {
+					case <-r.Context().Done():
+					case <-time.After(testCase.Sleep):
+					}
+				}
+				if n <= testCase.Failures {
+					w.WriteHeader(testCase.Code)
+					json.NewEncoder(w).Encode(Status{Code: testCase.Code, Message: "failing"})
+					return
+				}
+				json.NewEncoder(w).Encode(Status{Success: true, Code: http.StatusOK})
+			}))
+			defer server.Close()
+
+			policy := testCase.Policy
+			policy.Backoff = time.Millisecond
+
+			statuses, err := Client{
+				Addresses: []string{server.URL},
+				Retry:     policy,
+			}.Health()
+
+			if (err != nil) != testCase.Error {
+				t.Error(testCase, err)
+			}
+			if err == nil && (statuses[0] == nil || !statuses[0].Success) {
+				t.Error(testCase, statuses[0])
+			}
+			if n := atomic.LoadInt32(&requests); n != testCase.Requests {
+				t.Error(testCase, n)
+			}
+		}()
+	}
+}
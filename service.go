@@ -27,6 +27,11 @@ import (
 	"github.com/joeycumines/go-detect-cycle/floyds"
 	"strings"
 	"net/http"
+	"crypto/tls"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type (
@@ -45,10 +50,29 @@ type (
 
 		uuid    [16]byte
 		started time.Time
+
+		workers []error
+
+		metrics *metrics
+
+		states      map[stateKey]State
+		subscribers map[*subscriber]struct{}
+		history     map[stateKey]*historyRing
+
+		build BuildInfo
+
+		// listenerFatal contains the FatalError of any stopped listeners, by name
+		listenerFatal map[string]FatalError
+		running       int
+
+		tls *tls.Config
+
+		// dependencyClient is used for `http` and `https` dependencies, if Config.DependencyTLSConfig is set
+		dependencyClient *http.Client
 	}
 
 	// FatalError models an error that occurred within the service, a non-nil Error would indicate that the server is
-	// stopped.
+	// stopped, or that a worker failed (see Config.WorkerFatal).
 	FatalError struct {
 		Error   error
 		Time    time.Time
@@ -62,16 +86,21 @@ func NewService(config Config) (*Service, error) {
 		return nil, fmt.Errorf("kubestatus.NewService failed validation for config: %s", err.Error())
 	}
 
+	tlsConfig, err := config.serverTLSConfig()
+	if err != nil {
+		return nil, fmt.Errorf("kubestatus.NewService failed to configure tls: %s", err.Error())
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	service := &Service{
+		tls:    tlsConfig,
 		config: config,
 		ctx:    ctx,
 		cancel: cancel,
-		engine: gin.New(),
 		uuid:   config.UUID,
 		fatal: FatalError{
-			Error: errors.New("kubestatus.Service has not been started yet"),
+			Error: errNotStarted,
 		},
 	}
 
@@ -80,31 +109,15 @@ func NewService(config Config) (*Service, error) {
 		service.uuid = uuid.New()
 	}
 
-	service.engine.Use(config.GinHandlers...)
+	service.build = readBuildInfo(config.Build)
 
-	service.engine.GET(
-		"/healthz",
-		func(i *gin.Context) {
-			status := service.Health()
-			i.JSON(status.Code, status)
-		},
-	)
+	service.metrics = newMetrics(service)
 
-	service.engine.GET(
-		"/readiness",
-		func(i *gin.Context) {
-			UUIDs := make([]string, 0)
-			for _, UUID := range strings.Split(i.Query("uuids"), ",") {
-				UUID = strings.TrimSpace(UUID)
-				if UUID == "" {
-					continue
-				}
-				UUIDs = append(UUIDs, UUID)
-			}
-			status := service.Readiness(UUIDs...)
-			i.JSON(status.Code, status)
-		},
-	)
+	service.engine = service.newEngine(nil)
+
+	if config.DependencyTLSConfig != nil {
+		service.dependencyClient = newTLSClient(config.DependencyTLSConfig)
+	}
 
 	return service, nil
 }
@@ -134,40 +147,98 @@ func (s *Service) ensure() {
 	panic(err)
 }
 
-// Start initialises the http server, may only happen once, and runs the http server in the background
+// Start initialises the http server, may only happen once, and runs the http server in the background, note that
+// if a worker has already failed (see Config.WorkerFatal), the server will not be started, and it's error returned
 func (s *Service) Start() error {
 	s.ensure()
 	err := errors.New("kubestatus.Service.Start may only be called once")
 	s.init.Do(func() {
-		func() {
+		if err = func() error {
 			s.mutex.Lock()
 			defer s.mutex.Unlock()
+			if s.fatal.Error != errNotStarted {
+				// a worker failed before the service was started
+				return s.fatal.Error
+			}
 			s.started = time.Now()
 			s.fatal = FatalError{}
-		}()
-		go s.start()
+			s.running = len(s.config.listeners())
+			return nil
+		}(); err != nil {
+			return
+		}
+		s.start()
 		timer := time.NewTimer(s.config.StartWait)
 		defer timer.Stop()
 		select {
 		case <-s.ctx.Done():
 		case <-timer.C:
 		}
-		err = s.Fatal().Error
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		err = s.fatal.Error
+		for _, listener := range s.config.listeners() {
+			if fatal, ok := s.listenerFatal[listener.Name]; ok && err == nil {
+				err = fatal.Error
+			}
+		}
 	})
 	return err
 }
 
+// start runs the webhooks and each listener in the background
 func (s *Service) start() {
-	defer s.cancel()
-	fatalError := errors.New("unknown error")
+	s.startWebhooks()
+	for _, listener := range s.config.listeners() {
+		engine := s.engine
+		if len(listener.Endpoints) != 0 {
+			engine = s.newEngine(listener.Endpoints)
+		}
+		go s.serve(listener, engine)
+	}
+}
+
+// Go runs fn in a new goroutine, using the service's context, recovering any panic, where any failure (non-nil
+// error or panic) will be recorded against the service's health, identified by name, and will also be recorded as
+// the service's FatalError if Config.WorkerFatal is set
+func (s *Service) Go(name string, fn func(ctx context.Context) error) {
+	s.ensure()
+	if fn == nil {
+		panic(errors.New("kubestatus.Service.Go nil fn"))
+	}
+	go s.worker(name, fn)
+}
+
+func (s *Service) worker(name string, fn func(ctx context.Context) error) {
+	var err error
 	defer func() {
+		if err == nil {
+			return
+		}
 		stopped := time.Now()
-		s.mutex.Lock()
-		defer s.mutex.Unlock()
-		s.fatal = FatalError{
-			Error:   fatalError,
-			Time:    stopped,
-			Runtime: time.Duration(stopped.UnixNano() - s.started.UnixNano()),
+		s.logWorker(name, err)
+		err = fmt.Errorf("kubestatus.Service worker %q failed: %s", name, err.Error())
+		// the fatal error is logged after unlocking, as the mutex is shared with every probe
+		if fatal, ok := func() (FatalError, bool) {
+			s.mutex.Lock()
+			defer s.mutex.Unlock()
+			s.workers = append(s.workers, err)
+			if !s.config.WorkerFatal || (s.fatal.Error != nil && s.fatal.Error != errNotStarted) {
+				return FatalError{}, false
+			}
+			s.fatal = FatalError{
+				Error: err,
+				Time:  stopped,
+			}
+			if !s.started.IsZero() {
+				s.fatal.Runtime = time.Duration(stopped.UnixNano() - s.started.UnixNano())
+			}
+			return s.fatal, true
+		}(); ok {
+			s.logFatal(fatal)
+		}
+		if s.config.WorkerFatal {
+			s.cancel()
 		}
 	}()
 	defer func() {
@@ -175,14 +246,21 @@ func (s *Service) start() {
 		if r == nil {
 			return
 		}
-		fatalError = fmt.Errorf("recovered from panic (%T): %+v", r, r)
+		err = fmt.Errorf("recovered from panic (%T): %+v", r, r)
 	}()
-	if err := s.engine.Run(fmt.Sprintf("%s:%d", s.config.Hostname, s.config.Port)); err != nil {
-		fatalError = err
-	}
+	err = fn(s.ctx)
+}
+
+// Workers returns the errors for any workers (see Service.Go) that have failed, oldest first
+func (s *Service) Workers() []error {
+	s.ensure()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]error(nil), s.workers...)
 }
 
-// Ctx return the service's context, which will cancel once the service has been started then stopped
+// Ctx return the service's context, which will cancel once the service has been started then stopped, or a worker
+// fails (see Config.WorkerFatal), which will also shut down the http server
 func (s *Service) Ctx() context.Context {
 	s.ensure()
 	return s.ctx
@@ -198,51 +276,221 @@ func (s *Service) Fatal() FatalError {
 
 // Health returns the health of the service
 func (s *Service) Health() Status {
-	s.ensure()
-	err := s.Fatal().Error
-	if err == nil {
-		err = s.config.HealthHandler()
+	return s.HealthContext(context.Background())
+}
+
+// HealthContext is Health, with a context, which is used as the parent of any spans
+func (s *Service) HealthContext(ctx context.Context) Status {
+	status, _ := s.Evaluate(ctx, EndpointHealth, EvaluateOptions{})
+	return status
+}
+
+// endpointError returns the fatal error for an endpoint, or for health, the first failed worker, if any, which take
+// precedence over the endpoint's checks
+func (s *Service) endpointError(endpoint Endpoint) error {
+	err := s.fatalError(endpoint)
+	if err == nil && endpoint == EndpointHealth {
+		if workers := s.Workers(); len(workers) != 0 {
+			err = withReason(workers[0], ReasonWorkerFailed)
+		}
 	}
-	return NewStatus(s.uuid, s.started, err)
+	return err
+}
+
+func (s *Service) health(ctx context.Context, e *evaluation) Status {
+	if err := s.endpointError(EndpointHealth); err != nil {
+		e.err = err
+		return s.newStatus(err)
+	}
+
+	// test the local health handler, then the named health checks
+	e.check(ctx, HandlerCheckName, s.config.HealthHandler)
+	for _, check := range s.config.HealthChecks {
+		e.check(ctx, check.Name, check.Handler)
+	}
+
+	return s.newStatus(e.err)
 }
 
 // Readiness returns the readiness of the service, taking any number of previous UUIDs (oldest first)
 func (s *Service) Readiness(UUIDs ... string) Status {
-	s.ensure()
+	return s.ReadinessContext(context.Background(), UUIDs...)
+}
+
+// ReadinessContext is Readiness, with a context, which is used as the parent of any spans, and for any dependencies
+func (s *Service) ReadinessContext(ctx context.Context, UUIDs ... string) Status {
+	status, _ := s.Evaluate(ctx, EndpointReadiness, EvaluateOptions{UUIDs: UUIDs})
+	return status
+}
 
+func (s *Service) readiness(ctx context.Context, e *evaluation) Status {
 	// test for fatal error
-	if err := s.Fatal().Error; err != nil {
-		return NewStatus(s.uuid, s.started, err)
+	if err := s.endpointError(EndpointReadiness); err != nil {
+		e.err = err
+		return s.newStatus(e.err)
 	}
 
-	UUIDs = append(UUIDs, uuid.UUID(s.uuid).String())
+	UUIDs := append(append([]string(nil), e.options.UUIDs...), uuid.UUID(s.uuid).String())
 
 	// test for circular references
 	cycle := floyds.NewBranchingDetector(UUIDs[0], nil)
 	for _, UUID := range UUIDs[1:] {
 		cycle = cycle.Hare(UUID)
 		if !cycle.Ok() {
-			status := NewStatus(
-				s.uuid,
-				s.started,
-				fmt.Errorf("cyclic dependency detected for UUID list: %s", strings.Join(UUIDs, ",")),
-			)
-			status.Code = http.StatusLoopDetected
-			return status
+			e.err = &StatusError{
+				Code:   http.StatusLoopDetected,
+				Reason: ReasonCycleDetected,
+				Err:    fmt.Errorf("cyclic dependency detected for UUID list: %s", strings.Join(UUIDs, ",")),
+			}
+			return s.newStatus(e.err)
 		}
 	}
 
-	// test the local readiness handler
-	if err := s.config.ReadinessHandler(); err != nil {
-		return NewStatus(s.uuid, s.started, err)
+	// test the local readiness handler, then the named readiness checks
+	e.check(ctx, HandlerCheckName, s.config.ReadinessHandler)
+	for _, check := range s.config.ReadinessChecks {
+		e.check(ctx, check.Name, check.Handler)
 	}
 
-	// test the remote readiness handler, which passes down the UUID list for circular ref checking
-	if _, err := (Client{Addresses: s.config.Dependencies, UUIDs: UUIDs}).Readiness(); err != nil {
-		return NewStatus(s.uuid, s.started, err)
+	// test the dependencies, which pass down the UUID list for circular ref checking
+	for _, dependency := range s.config.Dependencies {
+		e.dependency(ctx, dependency, UUIDs)
 	}
 
-	return NewStatus(s.uuid, s.started, nil)
+	return s.newStatus(e.err)
+}
+
+// Check returns the status of a single check for the given endpoint, which may be a named check, or
+// HandlerCheckName, it will also fail if there is a fatal error, or return false if there is no such check
+func (s *Service) Check(endpoint Endpoint, name string) (Status, bool) {
+	s.ensure()
+	var checks []Check
+	switch endpoint {
+	case EndpointHealth:
+		checks = append([]Check{{Name: HandlerCheckName, Handler: s.config.HealthHandler}}, s.config.HealthChecks...)
+	case EndpointReadiness:
+		checks = append([]Check{{Name: HandlerCheckName, Handler: s.config.ReadinessHandler}}, s.config.ReadinessChecks...)
+	}
+	for _, check := range checks {
+		if check.Name != name {
+			continue
+		}
+		err := s.endpointError(endpoint)
+		if err == nil {
+			err = withReason(s.check(context.Background(), endpoint, check.Name, check.Handler), ReasonCheckFailed)
+		}
+		return s.newStatus(err), true
+	}
+	return Status{}, false
+}
+
+// observe records the overall status of an endpoint, where err is the full (unredacted) error, if any
+func (s *Service) observe(ctx context.Context, endpoint Endpoint, status Status, err error, duration time.Duration) {
+	s.metrics.observe(endpoint, status)
+	if err == nil && !status.Success {
+		err = errors.New(status.Message)
+	}
+	s.transition(ctx, stateKey{endpoint: endpoint}, err, duration, &status)
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		attribute.String("kubestatus.endpoint", string(endpoint)),
+		attribute.String("kubestatus.uuid", status.UUID),
+		attribute.Int("kubestatus.code", status.Code),
+	)
+	if !status.Success {
+		span.SetStatus(codes.Error, status.Message)
+	}
+}
+
+// check runs a single check handler, recording the outcome
+func (s *Service) check(ctx context.Context, endpoint Endpoint, name string, handler func() error) error {
+	_, span := s.tracer().Start(
+		ctx,
+		"kubestatus.Service.check",
+		trace.WithAttributes(
+			attribute.String("kubestatus.endpoint", string(endpoint)),
+			attribute.String("kubestatus.check", name),
+		),
+	)
+	defer span.End()
+	start := time.Now()
+	err := handler()
+	duration := time.Since(start)
+	s.metrics.check(endpoint, name, duration, err)
+	s.transition(ctx, stateKey{endpoint: endpoint, check: name}, err, duration, nil)
+	if err != nil {
+		span.SetStatus(codes.Error, s.redact(err.Error()))
+	}
+	return err
+}
+
+// dependency checks the readiness of a single dependency, recording the outcome
+func (s *Service) dependency(ctx context.Context, address string, UUIDs []string) (err error) {
+	ctx, span := s.tracer().Start(
+		ctx,
+		"kubestatus.Service.dependency",
+		trace.WithAttributes(attribute.String("kubestatus.address", RedactURLUserinfo(address))),
+	)
+	defer span.End()
+	start := time.Now()
+	client := Client{
+		Addresses:      []string{address},
+		UUIDs:          UUIDs,
+		TracerProvider: s.config.TracerProvider,
+		Propagator:     s.config.Propagator,
+		Retry:          s.config.DependencyRetry,
+		Breaker:        s.config.DependencyBreaker,
+		TLSConfig:      s.config.DependencyTLSConfig,
+		Header:         s.config.DependencyHeader,
+		Credentials:    s.config.DependencyCredentials,
+		HTTPClient:     s.dependencyClient,
+
+		AllowInsecureCredentials: s.config.DependencyInsecureCredentials,
+	}
+	if isGRPCDependency(address) {
+		if err = s.config.DependencyBreaker.allow(address); err == nil {
+			// grpc dependencies pass down the UUID list via metadata
+			err = checkGRPCDependency(ctx, address, client)
+			if ctx.Err() != nil {
+				s.config.DependencyBreaker.abort(address)
+			} else {
+				s.config.DependencyBreaker.record(address, err)
+			}
+		}
+	} else {
+		_, err = client.GetContext(ctx, s.config.dependencyPath())
+	}
+	s.metrics.dependency(RedactURLUserinfo(address), time.Since(start), err)
+	if err != nil {
+		span.SetStatus(codes.Error, s.redact(err.Error()))
+	}
+	return err
+}
+
+func (s *Service) tracer() trace.Tracer {
+	return tracer(s.config.TracerProvider)
+}
+
+func (s *Service) propagator() propagation.TextMapPropagator {
+	return propagator(s.config.Propagator)
+}
+
+// extract returns the context of the request, including any propagated trace context
+func (s *Service) extract(req *http.Request) context.Context {
+	return s.propagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+}
+
+// newStatus creates a new Status for this service, see NewStatus
+func (s *Service) newStatus(err error) Status {
+	status := NewStatus(s.uuid, s.started, err)
+	if !status.Success {
+		status.Message = s.redact(status.Message)
+	}
+	status.Pod = s.config.Pod
+	if s.config.StatusBuild {
+		status.Build = s.build
+	}
+	return status
 }
 
 // UUID returns this service's UUID
@@ -17,8 +17,11 @@
 package kubestatus
 
 import (
+	"context"
 	"testing"
+	"time"
 	"errors"
+	"net/http"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -158,3 +161,147 @@ func TestNewService_configUUID(t *testing.T) {
 		t.Fatal("unexpected service", service.uuid)
 	}
 }
+
+func TestService_Go(t *testing.T) {
+	config := NewConfig()
+	config.Port = 9060
+	config.GinHandlers = nil
+	gin.SetMode(gin.ReleaseMode)
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.HealthHandler = func() error {
+		return nil
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	service.Go("ok", func(ctx context.Context) error {
+		defer close(done)
+		return nil
+	})
+	<-done
+	if health := service.Health(); !health.Success {
+		t.Fatal(health)
+	}
+
+	done = make(chan struct{})
+	service.Go("some_worker", func(ctx context.Context) error {
+		defer close(done)
+		panic("some_panic")
+	})
+	<-done
+	for i := 0; i < 100 && len(service.Workers()) == 0; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	health := service.Health()
+	if health.Success || health.Code != 503 ||
+		health.Message != `kubestatus.Service worker "some_worker" failed: recovered from panic (string): some_panic` {
+		t.Error(health)
+	}
+	if readiness := service.Readiness(); !readiness.Success {
+		t.Error(readiness)
+	}
+	if service.Fatal().Error != nil || service.Ctx().Err() != nil {
+		t.Error("expected the service to still be running")
+	}
+}
+
+func TestService_Go_workerFatal(t *testing.T) {
+	config := NewConfig()
+	config.Port = 9061
+	config.GinHandlers = nil
+	gin.SetMode(gin.ReleaseMode)
+	config.WorkerFatal = true
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.HealthHandler = func() error {
+		return nil
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	service.Go("some_worker", func(ctx context.Context) error {
+		return errors.New("some_error")
+	})
+	select {
+	case <-service.Ctx().Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the service context to be cancelled")
+	}
+	if err := service.Fatal().Error; err == nil || err.Error() != `kubestatus.Service worker "some_worker" failed: some_error` {
+		t.Error(err)
+	}
+	if readiness := service.Readiness(); readiness.Success {
+		t.Error(readiness)
+	}
+
+	// the server is shut down
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		resp, err := http.Get("http://localhost:9061/healthz")
+		if err != nil {
+			break
+		}
+		resp.Body.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("expected the server to be shut down")
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if err := service.Fatal().Error; err == nil || err.Error() != `kubestatus.Service worker "some_worker" failed: some_error` {
+		t.Error(err)
+	}
+}
+
+func TestService_Go_workerFatalBeforeStart(t *testing.T) {
+	config := NewConfig()
+	config.Port = 9073
+	config.Logger = nil
+	config.WorkerFatal = true
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.HealthHandler = func() error {
+		return nil
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service.Go("some_worker", func(ctx context.Context) error {
+		return errors.New("some_error")
+	})
+	select {
+	case <-service.Ctx().Done():
+	case <-time.After(time.Second):
+		t.Fatal("expected the service context to be cancelled")
+	}
+
+	fatal := service.Fatal()
+	if fatal.Error == nil || fatal.Error.Error() != `kubestatus.Service worker "some_worker" failed: some_error` || fatal.Runtime != 0 {
+		t.Error(fatal)
+	}
+	if err := service.Start(); err != fatal.Error {
+		t.Error(err)
+	}
+	if err := service.Fatal().Error; err != fatal.Error {
+		t.Error(err)
+	}
+	if _, err := http.Get("http://localhost:9073/healthz"); err == nil {
+		t.Error("expected the server not to be started")
+	}
+}
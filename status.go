@@ -17,11 +17,33 @@
 package kubestatus
 
 import (
+	"errors"
 	"time"
 	guuid "github.com/google/uuid"
 	"net/http"
 )
 
+type (
+	// Reason is a machine-readable reason for a Status
+	Reason string
+
+	// StatusError may be returned (or wrapped) by any handler or check, to control the Status, e.g. to respond with
+	// 429 Too Many Requests and a Retry-After header while shedding load, see NewStatus
+	StatusError struct {
+		// Code is the HTTP status code, which must be 400 or greater, defaults to 503 Service Unavailable
+		Code int
+
+		// RetryAfter may be set to include a Retry-After header (rounded up to the nearest second)
+		RetryAfter time.Duration
+
+		// Reason may be set to a machine-readable reason
+		Reason Reason
+
+		// Err is the underlying error, which provides the message
+		Err error
+	}
+)
+
 // Status is the response object returned by all endpoints
 type Status struct {
 	// Code is the HTTP status code
@@ -41,9 +63,46 @@ type Status struct {
 
 	// UUID is a per-process uuid value in the format xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
 	UUID string `json:"uuid"`
+
+	// Pod identifies the Kubernetes pod running the service, it's only set if configured via Config.Pod
+	Pod PodInfo `json:"pod,omitzero"`
+
+	// Build identifies the build of the service, it's only set if enabled via Config.StatusBuild
+	Build BuildInfo `json:"build,omitzero"`
+
+	// Reason is a machine-readable reason, for failures, see StatusError
+	Reason Reason `json:"reason,omitempty"`
+
+	// RetryAfter is the number of seconds the caller should wait before retrying, also sent as the Retry-After
+	// header, see StatusError
+	RetryAfter int64 `json:"retryAfter,omitempty"`
+}
+
+// Error implements error, returning the message of Err, or otherwise the Reason, or status text of the Code
+func (e *StatusError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	if e.Reason != "" {
+		return string(e.Reason)
+	}
+	return http.StatusText(e.code())
+}
+
+// Unwrap returns Err
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+func (e *StatusError) code() int {
+	if e.Code < 400 {
+		return http.StatusServiceUnavailable
+	}
+	return e.Code
 }
 
-// NewStatus creates a new Status
+// NewStatus creates a new Status, any non-nil error results in a 503 Service Unavailable, unless it is (or wraps) a
+// *StatusError, which may set the Code, RetryAfter and Reason
 func NewStatus(uuid [16]byte, started time.Time, err error) Status {
 	startedTS := started.UnixNano()
 	result := Status{
@@ -58,6 +117,14 @@ func NewStatus(uuid [16]byte, started time.Time, err error) Status {
 		result.Code = http.StatusServiceUnavailable
 		result.Message = err.Error()
 		result.Success = false
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			result.Code = statusErr.code()
+			result.Reason = statusErr.Reason
+			if statusErr.RetryAfter > 0 {
+				result.RetryAfter = int64((statusErr.RetryAfter + time.Second - 1) / time.Second)
+			}
+		}
 	}
 	return result
 }
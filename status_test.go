@@ -0,0 +1,117 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewStatus_statusError(t *testing.T) {
+	for _, testCase := range []struct {
+		Err        error
+		Code       int
+		Message    string
+		Reason     Reason
+		RetryAfter int64
+	}{
+		{nil, 200, "OK", "", 0},
+		{errors.New("failed"), 503, "failed", "", 0},
+		{&StatusError{Code: http.StatusTooManyRequests, RetryAfter: time.Millisecond * 1500, Reason: "Overloaded", Err: errors.New("shedding")}, 429, "shedding", "Overloaded", 2},
+		{fmt.Errorf("check: %w", &StatusError{Code: http.StatusTooManyRequests, RetryAfter: time.Second}), 429, "check: Too Many Requests", "", 1},
+		{&StatusError{Reason: "Overloaded"}, 503, "Overloaded", "Overloaded", 0},
+		{&StatusError{Code: http.StatusOK, Err: errors.New("invalid code")}, 503, "invalid code", "", 0},
+	} {
+		status := NewStatus([16]byte{}, time.Now(), testCase.Err)
+		if status.Code != testCase.Code ||
+			status.Message != testCase.Message ||
+			status.Reason != testCase.Reason ||
+			status.RetryAfter != testCase.RetryAfter ||
+			status.Success != (testCase.Err == nil) {
+			t.Error(testCase.Err, status)
+		}
+	}
+
+	inner := errors.New("inner")
+	if err := (&StatusError{Err: inner}); !errors.Is(err, inner) {
+		t.Error(err)
+	}
+}
+
+func TestService_statusError(t *testing.T) {
+	config := NewConfig()
+	config.Logger = nil
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.ReadinessChecks = []Check{
+		{
+			Name: "load",
+			Handler: func() error {
+				return &StatusError{Code: http.StatusTooManyRequests, RetryAfter: time.Second * 5, Reason: "Overloaded", Err: errors.New("shedding load")}
+			},
+		},
+	}
+	config.Authorizer = BearerTokenAuthorizer("token")
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pretend we started
+	func() {
+		service.mutex.Lock()
+		defer service.mutex.Unlock()
+		service.fatal = FatalError{}
+	}()
+
+	for _, testCase := range []struct {
+		Target string
+		Accept string
+		Token  string
+	}{
+		{"/readiness", "", "token"},
+		{"/readiness", "text/plain", "token"},
+		{"/readiness?verbose", "", "token"},
+		{"/readiness/load", "", "token"},
+		{"/readiness", "", ""},
+	} {
+		req := httptest.NewRequest(http.MethodGet, testCase.Target, nil)
+		if testCase.Accept != "" {
+			req.Header.Set("Accept", testCase.Accept)
+		}
+		if testCase.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+testCase.Token)
+		}
+		w := httptest.NewRecorder()
+		service.engine.ServeHTTP(w, req)
+		if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "5" {
+			t.Error(testCase, w.Code, w.Header(), w.Body.String())
+		}
+	}
+
+	if status := service.Readiness(); status.Message != "load: shedding load" || status.Reason != "Overloaded" || status.RetryAfter != 5 {
+		t.Error(status)
+	}
+}
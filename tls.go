@@ -0,0 +1,139 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// certificateReloader loads a certificate and key pair from files, reloading them whenever either file is modified,
+// so that rotated certificates (e.g. mounted Kubernetes secrets) are picked up without a restart
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mutex       sync.Mutex
+	certificate *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+}
+
+func newCertificateReloader(certFile, keyFile string) (*certificateReloader, error) {
+	r := &certificateReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if _, err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load returns the current certificate, reloading it if either file has changed, the mutex must not be held
+func (r *certificateReloader) load() (*tls.Certificate, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	certInfo, certErr := os.Stat(r.certFile)
+	keyInfo, keyErr := os.Stat(r.keyFile)
+	if certErr == nil && keyErr == nil && r.certificate != nil &&
+		certInfo.ModTime().Equal(r.certModTime) && keyInfo.ModTime().Equal(r.keyModTime) {
+		return r.certificate, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		if r.certificate != nil {
+			// keep serving the previous certificate, e.g. while only one of the files has been rotated
+			return r.certificate, nil
+		}
+		return nil, err
+	}
+
+	r.certificate = &certificate
+	if certErr == nil {
+		r.certModTime = certInfo.ModTime()
+	}
+	if keyErr == nil {
+		r.keyModTime = keyInfo.ModTime()
+	}
+
+	return r.certificate, nil
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.load()
+}
+
+// TLS returns true if the server will be served over TLS, see Config.TLSConfig and Config.TLSCertFile
+func (c Config) TLS() bool {
+	return c.TLSConfig != nil || c.TLSCertFile != ""
+}
+
+// serverTLSConfig builds the tls config for the server, loading any configured files, or returns nil if TLS is not
+// enabled
+func (c Config) serverTLSConfig() (*tls.Config, error) {
+	if !c.TLS() {
+		return nil, nil
+	}
+
+	var config *tls.Config
+	if c.TLSConfig != nil {
+		config = c.TLSConfig.Clone()
+	} else {
+		config = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	if c.TLSCertFile != "" {
+		reloader, err := newCertificateReloader(c.TLSCertFile, c.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certificate: %s", err.Error())
+		}
+		// the reloaded certificate takes precedence over any in TLSConfig
+		config.Certificates = nil
+		config.GetCertificate = reloader.GetCertificate
+	}
+
+	if c.TLSClientCAFile != "" {
+		b, err := os.ReadFile(c.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client CA: %s", err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, fmt.Errorf("no certificates found in client CA %q", c.TLSClientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		if c.TLSClientCertRequired {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+
+	if len(config.Certificates) == 0 && config.GetCertificate == nil && config.GetConfigForClient == nil {
+		return nil, errors.New("no server certificate configured")
+	}
+
+	return config, nil
+}
@@ -0,0 +1,186 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig_URL_tls(t *testing.T) {
+	config := NewConfig()
+	if URL := config.URL(); URL != "http://localhost:8080" {
+		t.Fatal(URL)
+	}
+	config.TLSConfig = &tls.Config{}
+	if URL := config.URL(); URL != "https://localhost:8080" {
+		t.Fatal(URL)
+	}
+	config.TLSConfig = nil
+	config.TLSCertFile = "cert.pem"
+	if URL := config.URL(); URL != "https://localhost:8080" {
+		t.Fatal(URL)
+	}
+}
+
+func TestConfig_Validate_tls(t *testing.T) {
+	for _, testCase := range []struct {
+		Modify func(config *Config)
+		Error  string
+	}{
+		{func(config *Config) {}, ""},
+		{func(config *Config) { config.TLSCertFile = "cert.pem" }, "must be set together"},
+		{func(config *Config) { config.TLSKeyFile = "key.pem" }, "must be set together"},
+		{func(config *Config) { config.TLSClientCAFile = "ca.pem" }, "requires TLS"},
+		{func(config *Config) { config.TLSClientCertRequired = true }, "requires TLSClientCAFile"},
+		{func(config *Config) { config.TLSCertFile, config.TLSKeyFile = "cert.pem", "key.pem" }, ""},
+	} {
+		config := NewConfig()
+		config.HealthHandler = func() error { return nil }
+		config.ReadinessHandler = func() error { return nil }
+		testCase.Modify(&config)
+		err := config.Validate()
+		if testCase.Error == "" {
+			if err != nil {
+				t.Error(err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), testCase.Error) {
+			t.Error(testCase.Error, err)
+		}
+	}
+}
+
+func TestNewService_tlsInvalid(t *testing.T) {
+	config := NewConfig()
+	config.HealthHandler = func() error { return nil }
+	config.ReadinessHandler = func() error { return nil }
+	config.TLSCertFile = "missing-cert.pem"
+	config.TLSKeyFile = "missing-key.pem"
+	if _, err := NewService(config); err == nil || !strings.Contains(err.Error(), "failed to configure tls") {
+		t.Fatal(err)
+	}
+	config.TLSCertFile, config.TLSKeyFile = "", ""
+	config.TLSConfig = &tls.Config{}
+	if _, err := NewService(config); err == nil || !strings.Contains(err.Error(), "no server certificate") {
+		t.Fatal(err)
+	}
+}
+
+func TestService_tls(t *testing.T) {
+	dir, err := os.MkdirTemp("", "kubestatus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cert, key := generateCertificate(t, "original", time.Now().Add(time.Hour))
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	caFile := filepath.Join(dir, "ca.pem")
+	for file, b := range map[string][]byte{certFile: cert, keyFile: key, caFile: cert} {
+		if err := os.WriteFile(file, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// files take precedence over TLSConfig, and client certificates are only verified if given, by default
+	tlsConfig, err := Config{
+		TLSConfig:       &tls.Config{Certificates: []tls.Certificate{{}}},
+		TLSCertFile:     certFile,
+		TLSKeyFile:      keyFile,
+		TLSClientCAFile: caFile,
+	}.serverTLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tlsConfig.Certificates != nil || tlsConfig.GetCertificate == nil ||
+		tlsConfig.ClientAuth != tls.VerifyClientCertIfGiven {
+		t.Error(tlsConfig)
+	}
+
+	config := NewConfig()
+	config.Port = 9069
+	config.Logger = nil
+	config.HealthHandler = func() error { return nil }
+	config.ReadinessHandler = func() error { return nil }
+	config.TLSCertFile = certFile
+	config.TLSKeyFile = keyFile
+	config.TLSClientCAFile = caFile
+	config.TLSClientCertRequired = true
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	clientPair, err := tls.X509KeyPair(cert, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(cert)
+
+	get := func(tlsConfig *tls.Config) (*http.Response, error) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+		resp, err := client.Get(config.URL() + "/healthz")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return resp, err
+	}
+
+	resp, err := get(&tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientPair}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || resp.TLS.PeerCertificates[0].Subject.CommonName != "original" {
+		t.Fatal(resp.StatusCode, resp.TLS.PeerCertificates[0].Subject)
+	}
+
+	// client certificates are required
+	if _, err := get(&tls.Config{RootCAs: roots}); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// rotated certificates are reloaded
+	rotatedCert, rotatedKey := generateCertificate(t, "rotated", time.Now().Add(time.Hour))
+	if err := os.WriteFile(certFile, rotatedCert, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, rotatedKey, 0600); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	os.Chtimes(keyFile, future, future)
+
+	resp, err = get(&tls.Config{InsecureSkipVerify: true, Certificates: []tls.Certificate{clientPair}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.TLS.PeerCertificates[0].Subject.CommonName != "rotated" {
+		t.Fatal(resp.TLS.PeerCertificates[0].Subject)
+	}
+}
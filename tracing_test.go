@@ -0,0 +1,85 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"github.com/gin-gonic/gin"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestService_tracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(context.Background())
+
+	newService := func(port int, dependencies ...string) *Service {
+		config := NewConfig()
+		config.Port = port
+		config.GinHandlers = nil
+		gin.SetMode(gin.ReleaseMode)
+		config.TracerProvider = provider
+		config.Dependencies = dependencies
+		config.HealthHandler = func() error {
+			return nil
+		}
+		config.ReadinessHandler = func() error {
+			return nil
+		}
+		service, err := NewService(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := service.Start(); err != nil {
+			t.Fatal(err)
+		}
+		return service
+	}
+
+	newService(9065, "http://localhost:9066")
+	newService(9066)
+
+	if _, err := (Client{
+		Addresses:      []string{"http://localhost:9065"},
+		TracerProvider: provider,
+	}).Readiness(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name)
+		if span.SpanContext.TraceID() != spans[0].SpanContext.TraceID() {
+			t.Error("expected a single trace", span.Name)
+		}
+	}
+	sort.Strings(names)
+	if actual := strings.Join(names, ","); actual != "kubestatus.Client.Get,"+
+		"kubestatus.Client.Get,"+
+		"kubestatus.Service.Readiness,"+
+		"kubestatus.Service.Readiness,"+
+		"kubestatus.Service.check,"+
+		"kubestatus.Service.check,"+
+		"kubestatus.Service.dependency" {
+		t.Error(actual)
+	}
+}
@@ -0,0 +1,267 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"github.com/gin-gonic/gin"
+)
+
+type (
+	// EvaluateOptions configures kubestatus.Service.Evaluate
+	EvaluateOptions struct {
+		// UUIDs are any previous UUIDs (oldest first), for readiness
+		UUIDs []string
+
+		// Exclude are names of checks to skip, which may be HandlerCheckName, the name of a check, or the address
+		// of a dependency (with any userinfo redacted, see kubestatus.RedactURLUserinfo), note that evaluations with
+		// exclusions aren't recorded as the state of the endpoint
+		Exclude []string
+
+		// Verbose will evaluate every check, rather than stopping at the first failure
+		Verbose bool
+	}
+
+	// CheckResult is the result of a single check (including dependencies) within an evaluation
+	CheckResult struct {
+		// Name is the name of the check, or the address of the dependency, with any userinfo redacted
+		Name string `json:"name"`
+
+		// Success will be true if the check passed, or was excluded
+		Success bool `json:"success"`
+
+		// Excluded will be true if the check was skipped, see EvaluateOptions.Exclude
+		Excluded bool `json:"excluded,omitempty"`
+
+		// Message will be either 'OK', or the error message
+		Message string `json:"message"`
+
+		// dependency will be true if the check is of a dependency, see Config.Dependencies
+		dependency bool
+	}
+
+	// VerboseStatus is the JSON response for `?verbose`
+	VerboseStatus struct {
+		Status
+
+		// Checks are the results of each check, in order of evaluation
+		Checks []CheckResult `json:"checks"`
+	}
+
+	// evaluation tracks the state of evaluating a single endpoint
+	evaluation struct {
+		service  *Service
+		endpoint Endpoint
+		options  EvaluateOptions
+		err      error
+		results  []CheckResult
+	}
+)
+
+// Evaluate evaluates the health or readiness of the service, returning the overall status, and the results of each
+// check that was evaluated, in order
+func (s *Service) Evaluate(ctx context.Context, endpoint Endpoint, options EvaluateOptions) (Status, []CheckResult) {
+	s.ensure()
+
+	e := &evaluation{
+		service:  s,
+		endpoint: endpoint,
+		options:  options,
+	}
+
+	var (
+		evaluate func(ctx context.Context, e *evaluation) Status
+		spanName string
+	)
+	switch endpoint {
+	case EndpointHealth:
+		evaluate, spanName = s.health, "kubestatus.Service.Health"
+	case EndpointReadiness:
+		evaluate, spanName = s.readiness, "kubestatus.Service.Readiness"
+	default:
+		status := s.newStatus(fmt.Errorf("unknown endpoint: %s", endpoint))
+		status.Code = http.StatusNotFound
+		return status, nil
+	}
+
+	ctx, span := s.tracer().Start(ctx, spanName)
+	defer span.End()
+
+	start := time.Now()
+	status := evaluate(ctx, e)
+	if len(options.Exclude) == 0 {
+		s.observe(ctx, endpoint, status, e.err, time.Since(start))
+	}
+
+	return status, e.results
+}
+
+func (e *evaluation) excluded(name string) bool {
+	for _, exclude := range e.options.Exclude {
+		if exclude == name {
+			return true
+		}
+	}
+	return false
+}
+
+// run records the result of fn, unless it is excluded, or a previous check failed and we aren't verbose, where
+// prefix indicates if the name should prefix the error, reason is the default reason, for the overall status, and
+// dependency indicates if the check is of a dependency
+func (e *evaluation) run(name string, prefix bool, dependency bool, reason Reason, fn func() error) {
+	result := CheckResult{Name: name, Success: true, Message: "OK", dependency: dependency}
+	if e.excluded(name) {
+		result.Excluded = true
+		e.results = append(e.results, result)
+		return
+	}
+	if e.err != nil && !e.options.Verbose {
+		return
+	}
+	if err := fn(); err != nil {
+		result.Success = false
+		result.Message = e.service.redact(err.Error())
+		if e.err == nil {
+			if prefix {
+				// wrap, to preserve any *StatusError
+				err = fmt.Errorf("%s: %w", name, err)
+			}
+			e.err = withReason(err, reason)
+		}
+	}
+	e.results = append(e.results, result)
+}
+
+func (e *evaluation) check(ctx context.Context, name string, handler func() error) {
+	e.run(name, name != HandlerCheckName, false, ReasonCheckFailed, func() error {
+		return e.service.check(ctx, e.endpoint, name, handler)
+	})
+}
+
+func (e *evaluation) dependency(ctx context.Context, address string, UUIDs []string) {
+	e.run(RedactURLUserinfo(address), false, true, ReasonDependencyFailed, func() error {
+		return e.service.dependency(ctx, address, UUIDs)
+	})
+}
+
+// splitQuery returns all non-empty values for a query parameter, which may be repeated, or comma separated
+func splitQuery(i *gin.Context, key string) []string {
+	values := make([]string, 0)
+	for _, value := range i.QueryArray(key) {
+		for _, value := range strings.Split(value, ",") {
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (s *Service) healthHandler(i *gin.Context) {
+	s.endpointHandler(i, EndpointHealth, EvaluateOptions{})
+}
+
+func (s *Service) readinessHandler(i *gin.Context) {
+	s.endpointHandler(i, EndpointReadiness, EvaluateOptions{UUIDs: splitQuery(i, "uuids")})
+}
+
+// endpointHandler serves an endpoint, supporting the `exclude` and `verbose` query parameters, where verbose will
+// render plaintext unless JSON is preferred, otherwise the format is negotiated (see negotiate)
+func (s *Service) endpointHandler(i *gin.Context, endpoint Endpoint, options EvaluateOptions) {
+	options.Exclude = splitQuery(i, "exclude")
+	_, options.Verbose = i.GetQuery("verbose")
+
+	format := negotiate(i)
+
+	if !s.authorized(i) {
+		// exclusions would reveal the names of checks
+		options.Exclude = nil
+		options.Verbose = false
+		status, _ := s.Evaluate(s.extract(i.Request), endpoint, options)
+		s.renderBare(i, format, status)
+		return
+	}
+
+	verbose := options.Verbose
+
+	// the health+json format lists every check
+	if format == MIMEHealthJSON {
+		options.Verbose = true
+	}
+
+	status, results := s.Evaluate(s.extract(i.Request), endpoint, options)
+
+	// health+json is rendered the same way regardless of verbose
+	if !verbose || format == MIMEHealthJSON {
+		s.render(i, format, status, results)
+		return
+	}
+
+	setHeaders(i, status)
+
+	if i.NegotiateFormat(gin.MIMEPlain, gin.MIMEJSON) == gin.MIMEJSON {
+		i.JSON(status.Code, VerboseStatus{Status: status, Checks: results})
+		return
+	}
+
+	i.String(status.Code, "%s", verboseText(endpoint, status, results))
+}
+
+// verboseText renders results in the style of the kube-apiserver's `/readyz?verbose`
+func verboseText(endpoint Endpoint, status Status, results []CheckResult) string {
+	var b strings.Builder
+	for _, result := range results {
+		switch {
+		case result.Excluded:
+			fmt.Fprintf(&b, "[+]%s excluded: ok\n", result.Name)
+		case result.Success:
+			fmt.Fprintf(&b, "[+]%s ok\n", result.Name)
+		default:
+			fmt.Fprintf(&b, "[-]%s failed: %s\n", result.Name, result.Message)
+		}
+	}
+	if status.Success {
+		fmt.Fprintf(&b, "%s check passed\n", endpoint)
+	} else {
+		fmt.Fprintf(&b, "%s check failed: %s\n", endpoint, status.Message)
+	}
+	return b.String()
+}
+
+// checkHandler serves a single check, see kubestatus.Service.Check, where unauthorized callers receive the bare
+// status of the whole endpoint, so that the names of checks aren't revealed
+func (s *Service) checkHandler(endpoint Endpoint) gin.HandlerFunc {
+	return func(i *gin.Context) {
+		if !s.authorized(i) {
+			status, _ := s.Evaluate(s.extract(i.Request), endpoint, EvaluateOptions{})
+			s.renderBare(i, negotiate(i), status)
+			return
+		}
+		status, ok := s.Check(endpoint, i.Param("check"))
+		if !ok {
+			status = s.newStatus(fmt.Errorf("unknown check: %s", i.Param("check")))
+			status.Code = http.StatusNotFound
+		}
+		s.render(i, negotiate(i), status, nil)
+	}
+}
@@ -0,0 +1,152 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestService_verbose(t *testing.T) {
+	var cacheCalls int32
+	config := NewConfig()
+	config.Logger = nil
+	config.HistorySize = DefaultHistorySize
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.ReadinessChecks = []Check{
+		{
+			Name: "database",
+			Handler: func() error {
+				return errors.New("not connected")
+			},
+		},
+		{
+			Name: "cache",
+			Handler: func() error {
+				atomic.AddInt32(&cacheCalls, 1)
+				return nil
+			},
+		},
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pretend we started
+	func() {
+		service.mutex.Lock()
+		defer service.mutex.Unlock()
+		service.fatal = FatalError{}
+	}()
+
+	get := func(target string, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		service.engine.ServeHTTP(w, req)
+		return w
+	}
+
+	// the default response stops at the first failure
+	if w := get("/readiness", ""); w.Code != 503 || atomic.LoadInt32(&cacheCalls) != 0 {
+		t.Error(w.Code, w.Body.String())
+	} else if status := decodeStatus(t, w); status.Message != "database: not connected" {
+		t.Error(status)
+	}
+
+	if w := get("/readiness?verbose", ""); w.Code != 503 || w.Body.String() != "[+]handler ok\n"+
+		"[-]database failed: not connected\n"+
+		"[+]cache ok\n"+
+		"readiness check failed: database: not connected\n" {
+		t.Errorf("%d\n%s", w.Code, w.Body.String())
+	}
+
+	if w := get("/readiness?verbose&exclude=database", ""); w.Code != 200 || w.Body.String() != "[+]handler ok\n"+
+		"[+]database excluded: ok\n"+
+		"[+]cache ok\n"+
+		"readiness check passed\n" {
+		t.Errorf("%d\n%s", w.Code, w.Body.String())
+	}
+
+	if w := get("/readiness?verbose", "application/json"); w.Code != 503 {
+		t.Error(w.Code, w.Body.String())
+	} else {
+		var status VerboseStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Fatal(err)
+		}
+		if status.Success || len(status.Checks) != 3 ||
+			status.Checks[1] != (CheckResult{Name: "database", Message: "not connected"}) {
+			t.Error(status)
+		}
+	}
+
+	if w := get("/healthz?verbose", ""); w.Code != 200 || w.Body.String() != "[+]handler ok\nhealth check passed\n" {
+		t.Errorf("%d\n%s", w.Code, w.Body.String())
+	}
+
+	for _, testCase := range []struct {
+		Target string
+		Code   int
+	}{
+		{"/readiness?exclude=database", 200},
+		{"/readiness?exclude=cache", 503},
+		{"/readiness?exclude=handler&exclude=database", 200},
+		{"/readiness/database", 503},
+		{"/readiness/cache", 200},
+		{"/readiness/handler", 200},
+		{"/readiness/missing", 404},
+		{"/healthz/handler", 200},
+		{"/healthz/database", 404},
+	} {
+		if w := get(testCase.Target, ""); w.Code != testCase.Code {
+			t.Error(testCase, w.Code, w.Body.String())
+		}
+	}
+
+	// evaluations with exclusions aren't recorded
+	if entries := service.History(HistoryFilter{Endpoint: EndpointReadiness, Check: ""}); len(entries) == 0 {
+		t.Error(entries)
+	} else {
+		for _, entry := range entries {
+			if entry.Check == "" && entry.New != "failing" {
+				t.Error(entry)
+			}
+		}
+	}
+}
+
+func decodeStatus(t *testing.T, w *httptest.ResponseRecorder) Status {
+	t.Helper()
+	var status Status
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatal(err)
+	}
+	return status
+}
@@ -0,0 +1,202 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultWebhookMaxAttempts = 3
+	DefaultWebhookBackoff     = time.Second
+	DefaultWebhookQueueSize   = 64
+	DefaultWebhookTimeout     = time.Second * 5
+)
+
+type (
+	// WebhookConfig configures POSTing a WebhookPayload to each URL whenever the health or readiness of the service
+	// changes, see Config.Webhook
+	WebhookConfig struct {
+		// URLs are the webhooks to notify, no notifications will be sent if empty
+		URLs []string
+
+		// MaxAttempts is the maximum number of attempts per URL and notification, defaults to
+		// DefaultWebhookMaxAttempts
+		MaxAttempts int
+
+		// Backoff is the delay before the first retry, which doubles for each subsequent retry, defaults to
+		// DefaultWebhookBackoff
+		Backoff time.Duration
+
+		// QueueSize is the maximum number of pending notifications, any more will be dropped, defaults to
+		// DefaultWebhookQueueSize
+		QueueSize int
+
+		// Timeout is the timeout for each request, defaults to DefaultWebhookTimeout
+		Timeout time.Duration
+	}
+
+	// WebhookPayload is the JSON body POSTed to webhooks
+	WebhookPayload struct {
+		// Endpoint is the endpoint that changed, either `health` or `readiness`
+		Endpoint Endpoint `json:"endpoint"`
+
+		// Old is the previous state, one of `unknown`, `ok` or `failing`
+		Old string `json:"old"`
+
+		// New is the current state, one of `ok` or `failing`
+		New string `json:"new"`
+
+		// Message will be either 'OK', or the error message
+		Message string `json:"message"`
+
+		// Time is a nanoseconds epoch indicating when the change was observed
+		Time int64 `json:"time"`
+
+		// UUID is the service's UUID
+		UUID string `json:"uuid"`
+	}
+)
+
+// startWebhooks subscribes to endpoint transitions, and delivers them to any configured webhooks in the background
+func (s *Service) startWebhooks() {
+	config := s.config.Webhook
+	if len(config.URLs) == 0 {
+		return
+	}
+
+	queueSize := config.QueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultWebhookQueueSize
+	}
+
+	queue := make(chan WebhookPayload, queueSize)
+
+	s.Subscribe(func(event Event) {
+		if event.Check != "" {
+			return
+		}
+		payload := WebhookPayload{
+			Endpoint: event.Endpoint,
+			Old:      event.Old.String(),
+			New:      event.New.String(),
+			Message:  "OK",
+			Time:     event.Time.UnixNano(),
+			UUID:     uuid.UUID(s.uuid).String(),
+		}
+		if event.Error != nil {
+			payload.Message = s.redact(event.Error.Error())
+		}
+		select {
+		case queue <- payload:
+		default:
+			s.logger().Warn("kubestatus webhook queue full, dropped notification", "endpoint", string(event.Endpoint))
+		}
+	})
+
+	s.Go("webhooks", func(ctx context.Context) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case payload := <-queue:
+				for _, URL := range config.URLs {
+					if err := config.send(ctx, URL, payload); err != nil {
+						s.logger().LogAttrs(
+							ctx,
+							slog.LevelWarn,
+							"kubestatus webhook failed",
+							slog.String("url", URL),
+							slog.String("error", err.Error()),
+						)
+					}
+				}
+			}
+		}
+	})
+}
+
+// send POSTs the payload to URL, retrying with backoff
+func (c WebhookConfig) send(ctx context.Context, URL string, payload WebhookPayload) error {
+	maxAttempts := c.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultWebhookMaxAttempts
+	}
+
+	backoff := c.Backoff
+	if backoff <= 0 {
+		backoff = DefaultWebhookBackoff
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultWebhookTimeout
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		err = func() error {
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodPost, URL, bytes.NewReader(body))
+			if err != nil {
+				return err
+			}
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if !statusOK(resp.StatusCode) {
+				return errors.New(resp.Status)
+			}
+			return nil
+		}()
+
+		if err == nil || attempt >= maxAttempts {
+			break
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed after %d attempt(s): %s", maxAttempts, err.Error())
+	}
+
+	return nil
+}
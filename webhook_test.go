@@ -0,0 +1,102 @@
+/*
+   Copyright 2018 Joseph Cumines
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+ */
+
+package kubestatus
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	"github.com/google/uuid"
+)
+
+func TestService_webhooks(t *testing.T) {
+	var requests int32
+	payloads := make(chan WebhookPayload, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first request fails, to exercise retries
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Error(r.Method, r.Header)
+		}
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Error(err)
+		}
+		payloads <- payload
+	}))
+	defer receiver.Close()
+
+	config := NewConfig()
+	config.Port = 9074
+	config.Logger = nil
+	config.HealthHandler = func() error {
+		return nil
+	}
+	config.ReadinessHandler = func() error {
+		return nil
+	}
+	config.Webhook = WebhookConfig{
+		URLs:    []string{receiver.URL},
+		Backoff: time.Millisecond,
+	}
+	service, err := NewService(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// not started, so not ready, but webhooks aren't delivered until the service is started
+	service.Readiness()
+
+	select {
+	case payload := <-payloads:
+		t.Error("unexpected payload", payload)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	service.Readiness()
+	service.Readiness()
+
+	select {
+	case payload := <-payloads:
+		if payload.Endpoint != EndpointReadiness || payload.Old != "failing" || payload.New != "ok" ||
+			payload.Message != "OK" || payload.UUID != uuid.UUID(service.UUID()).String() || payload.Time == 0 {
+			t.Error(payload)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a payload")
+	}
+
+	select {
+	case payload := <-payloads:
+		t.Error("unexpected payload", payload)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Error(n)
+	}
+}